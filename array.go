@@ -0,0 +1,102 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// arrayElements 将切片值展开为元素
+func arrayElements(value interface{}) ([]interface{}, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+	if _, ok := value.([]byte); ok {
+		return nil, false
+	}
+	elements := make([]interface{}, v.Len())
+	for i := range elements {
+		elements[i] = v.Index(i).Interface()
+	}
+	return elements, true
+}
+
+// postgresArray Postgres 数组参数，渲染为数组字面量 {"1","2"} 作为一个参数绑定，
+// 由数据库按比较的列推断元素类型，避免 ARRAY[?,?] 的参数被推断为 text[] 后与 int[] 等列比较失败
+type postgresArray []interface{}
+
+func (a postgresArray) Value() (driver.Value, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, e := range a {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if e == nil {
+			b.WriteString("NULL")
+			continue
+		}
+		text := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(fmt.Sprint(e))
+		b.WriteString(`"` + text + `"`)
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// buildArrayCondition 检查数组操作符的值，并转换为各数据库操作符需要的形式
+// ClickHouse 转换为 []interface{}，由操作符展开为多个参数；Postgres 转换为 postgresArray；MySQL 转换为 JSON 文本
+func buildArrayCondition(dbType DBType, condition *Condition) error {
+	switch condition.Operator {
+	case ArrayContains:
+		if _, ok := arrayElements(condition.Value); ok {
+			return fmt.Errorf("operator %s requires a single element, use %s for %T", ArrayContains, ArrayContainsAll, condition.Value)
+		}
+		if dbType == Mysql {
+			doc, err := json.Marshal(condition.Value)
+			if err != nil {
+				return err
+			}
+			condition.Value = string(doc)
+		}
+	case ArrayContainsAll, ArrayOverlaps:
+		elements, ok := arrayElements(condition.Value)
+		if !ok {
+			return fmt.Errorf("operator %s requires a slice value, got %T", condition.Operator, condition.Value)
+		}
+		if dbType == Mysql {
+			docs := make([]interface{}, len(elements))
+			for i, e := range elements {
+				doc, err := json.Marshal(e)
+				if err != nil {
+					return err
+				}
+				docs[i] = string(doc)
+			}
+			elements = docs
+		}
+		if dbType == Postgres {
+			condition.Value = postgresArray(elements)
+			return nil
+		}
+		condition.Value = elements
+	case ArrayLength:
+		switch reflect.ValueOf(condition.Value).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return fmt.Errorf("operator %s requires an integer value, got %T", ArrayLength, condition.Value)
+		}
+	}
+	return nil
+}
+
+// placeholders 生成 n 个逗号分隔的 ?
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
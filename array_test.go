@@ -0,0 +1,51 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+type arrayRow struct {
+	ID     uint
+	TagIDs []int64 `gorm:"type:integer[]"`
+}
+
+func TestPostgresArrayOperatorsBindOneParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator Operator
+		value    interface{}
+		want     string
+		wantArg  string
+	}{
+		{"contains all ints", ArrayContainsAll, []int64{1, 2}, `tag_ids @> $1`, `{"1","2"}`},
+		{"overlaps ints", ArrayOverlaps, []int{3}, `tag_ids && $1`, `{"3"}`},
+		{"escaped text", ArrayOverlaps, []string{`a"b`, `c\d`}, `tag_ids && $1`, `{"a\"b","c\\d"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, b := newFakeDB(t, Postgres)
+			var args []driver.NamedValue
+			b.query = func(query string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+				args = a
+				return nil, nil, nil
+			}
+			option := NewBuilderOption()
+			builder := NewConditionBuilder()
+			builder.AppendCondition("tag_ids", tt.operator, tt.value)
+			option.AppendBuilder(builder)
+			var rows []arrayRow
+			if err := NewDatabase(Postgres).Use(db).Where(option).Find(&rows).Error(); err != nil {
+				t.Fatal(err)
+			}
+			stmts := b.statements()
+			if len(stmts) != 1 || !strings.Contains(stmts[0], tt.want) {
+				t.Errorf("statements %q, want %q", stmts, tt.want)
+			}
+			if len(args) != 1 || args[0].Value != tt.wantArg {
+				t.Errorf("args = %v, want [%s]", args, tt.wantArg)
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package dac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// AuditConfig 表的审计配置
+type AuditConfig struct {
+	CreatedBy string   // 创建人列，默认 created_by，模型没有该字段时不填充
+	UpdatedBy string   // 修改人列，默认 updated_by
+	Record    bool     // 是否记录变更，需要通过 RegisterAuditSink 设置写入位置
+	Columns   []string // 记录变更的列，为空时记录所有列
+}
+
+// AuditTableInfo 设置表的审计配置，未实现该接口的表只填充 created_by、updated_by，不记录变更
+type AuditTableInfo interface {
+	TableInfo
+	Audit() AuditConfig
+}
+
+// AuditRecord 一行数据的变更记录
+type AuditRecord struct {
+	Table      string
+	PrimaryKey string // 主键值，联合主键以逗号分隔
+	Operation  string // create、update、delete
+	Actor      interface{}
+	Before     map[string]interface{} // 变更前的值，创建时为 nil
+	After      map[string]interface{} // 变更后的值，删除时为 nil
+	Changes    []string               // 值发生变化的列
+	Time       time.Time
+}
+
+// AuditSink 变更记录的写入位置，tx 与变更在同一个事务中
+type AuditSink interface {
+	WriteAudit(tx *Database, records []AuditRecord) error
+}
+
+var (
+	// ErrAuditTooManyRows 记录变更时匹配的行数超过 AuditMaxRows，语句不会执行
+	ErrAuditTooManyRows = errors.New("too many rows to audit")
+
+	// AuditMaxRows 记录变更时最多读取的变更前的行数，0 表示不限制
+	// Update、Updates、Delete 按条件批量修改时会先读取所有匹配的行，数量较大时应缩小条件或分批执行
+	AuditMaxRows = 10000
+)
+
+var (
+	auditSinkMu sync.RWMutex
+	auditSink   AuditSink
+)
+
+// RegisterAuditSink 设置变更记录的写入位置，为 nil 时不记录
+func RegisterAuditSink(sink AuditSink) {
+	auditSinkMu.Lock()
+	defer auditSinkMu.Unlock()
+	auditSink = sink
+}
+
+func getAuditSink() AuditSink {
+	auditSinkMu.RLock()
+	defer auditSinkMu.RUnlock()
+	return auditSink
+}
+
+// AuditLog 审计表的行，使用 AuditTableSink 前需要 AutoMigrate(&AuditLog{})
+type AuditLog struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement"`
+	Target     string    `gorm:"column:table_name;type:varchar(128);index"`
+	PrimaryKey string    `gorm:"type:varchar(255);index"`
+	Operation  string    `gorm:"type:varchar(16)"`
+	Actor      string    `gorm:"type:varchar(128)"`
+	Before     string    `gorm:"type:text"`
+	After      string    `gorm:"type:text"`
+	Changes    string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"type:timestamp"`
+}
+
+// TableName 默认的审计表
+func (AuditLog) TableName() string {
+	return "dac_audit_logs"
+}
+
+// AuditTableSink 将变更记录写入同一个数据库的审计表，与变更在同一个事务中提交
+type AuditTableSink struct {
+	Table string // 审计表，默认 dac_audit_logs
+}
+
+func (s AuditTableSink) WriteAudit(tx *Database, records []AuditRecord) error {
+	logs := make([]AuditLog, len(records))
+	for i, r := range records {
+		before, _ := json.Marshal(r.Before)
+		after, _ := json.Marshal(r.After)
+		changes, _ := json.Marshal(r.Changes)
+		logs[i] = AuditLog{
+			Target:     r.Table,
+			PrimaryKey: r.PrimaryKey,
+			Operation:  r.Operation,
+			Before:     string(before),
+			After:      string(after),
+			Changes:    string(changes),
+			CreatedAt:  r.Time,
+		}
+		if r.Actor != nil {
+			logs[i].Actor = fmt.Sprint(r.Actor)
+		}
+	}
+	db := tx.DB().Session(&gorm.Session{NewDB: true})
+	if s.Table != "" {
+		db = db.Table(s.Table)
+	}
+	return db.Create(&logs).Error
+}
+
+type actorKey struct{}
+
+// ContextWithActor 在 context 中设置操作人，通过 WithContext 传给 Database
+func ContextWithActor(ctx context.Context, actor interface{}) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext 获取 context 中的操作人
+func ActorFromContext(ctx context.Context) (interface{}, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	actor := ctx.Value(actorKey{})
+	return actor, actor != nil
+}
+
+// auditConfigOf 获取表的审计配置
+func auditConfigOf(s *schema.Schema) AuditConfig {
+	var cfg AuditConfig
+	if s == nil {
+		return cfg
+	}
+	if ti, ok := reflect.New(s.ModelType).Interface().(AuditTableInfo); ok {
+		cfg = ti.Audit()
+	}
+	if cfg.CreatedBy == "" {
+		cfg.CreatedBy = "created_by"
+	}
+	if cfg.UpdatedBy == "" {
+		cfg.UpdatedBy = "updated_by"
+	}
+	return cfg
+}
+
+// applyActor 创建时填充 created_by、updated_by，更新时填充 updated_by
+func (d *Database) applyActor(op operation, value interface{}) error {
+	if op != opCreate && op != opUpdate {
+		return nil
+	}
+	actor, ok := ActorFromContext(d.db.Statement.Context)
+	if !ok {
+		return nil
+	}
+	s, _ := d.target(value)
+	if s == nil {
+		return nil
+	}
+	cfg := auditConfigOf(s)
+	ctx := d.db.Statement.Context
+	set := func(column string, onlyZero bool) error {
+		field := s.LookUpField(column)
+		if field == nil {
+			return nil
+		}
+		switch m := value.(type) {
+		case map[string]interface{}:
+			if _, exists := m[column]; !exists || !onlyZero {
+				m[column] = actor
+			}
+			return nil
+		}
+		return eachStruct(value, func(rv reflect.Value) error {
+			if onlyZero {
+				if _, zero := field.ValueOf(ctx, rv); !zero {
+					return nil
+				}
+			}
+			return field.Set(ctx, rv, actor)
+		})
+	}
+	if op == opCreate {
+		if err := set(cfg.CreatedBy, true); err != nil {
+			return err
+		}
+	}
+	return set(cfg.UpdatedBy, false)
+}
+
+// updatedByColumn Update 单列时需要一起更新的修改人列
+func (d *Database) updatedByColumn() (string, interface{}, bool) {
+	actor, ok := ActorFromContext(d.db.Statement.Context)
+	if !ok {
+		return "", nil, false
+	}
+	s, _ := d.target(nil)
+	if s == nil {
+		return "", nil, false
+	}
+	column := auditConfigOf(s).UpdatedBy
+	if s.LookUpField(column) == nil {
+		return "", nil, false
+	}
+	return column, actor, true
+}
+
+// eachStruct 对结构体或结构体切片的每个元素调用 fn
+func eachStruct(value interface{}, fn func(rv reflect.Value) error) error {
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	switch rv.Kind() {
+	case reflect.Struct:
+		return fn(rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				if err := fn(elem); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// write 执行写操作，RetryWrites 标记过时出错会重试，成功后使表的缓存失效
+func (d *Database) write(op operation, value interface{}, exec func(tx *Database)) *Database {
+	defer d.invalidate(value)
+	d.retryWrite(func() {
+		d.writeOnce(op, value, exec)
+	})
+	return d
+}
+
+// writeOnce 执行一次写操作，表配置了记录变更时读取变更前后的行，与变更在同一个事务中写入 AuditSink
+func (d *Database) writeOnce(op operation, value interface{}, exec func(tx *Database)) *Database {
+	sink := getAuditSink()
+	s, _ := d.target(value)
+	cfg := auditConfigOf(s)
+	if sink == nil || s == nil || !cfg.Record || len(s.PrimaryFields) == 0 {
+		exec(d)
+		return d
+	}
+	table := s.Table
+	run := func(tx *Database) error {
+		var before []reflect.Value
+		if op != opCreate {
+			rows, err := tx.loadRows(s, value)
+			if err != nil {
+				return err
+			}
+			before = rows
+		}
+		exec(tx)
+		if tx.err != nil {
+			return tx.err
+		}
+		if tx.db.Error != nil {
+			return tx.db.Error
+		}
+		records, err := tx.auditRecords(op, s, table, cfg, value, before)
+		if err != nil || len(records) == 0 {
+			return err
+		}
+		return sink.WriteAudit(tx.clone(tx.db), records)
+	}
+	// ClickHouse 不支持事务，直接写入
+	if d.DBType == Clickhouse {
+		if err := run(d); err != nil && d.err == nil {
+			d.err = err
+		}
+		return d
+	}
+	var result *gorm.DB
+	err := d.db.Transaction(func(gtx *gorm.DB) error {
+		tx := d.clone(gtx)
+		err := run(tx)
+		result = tx.db
+		return err
+	})
+	if result != nil {
+		d.db = result
+	}
+	if err != nil {
+		d.err = err
+	}
+	return d
+}
+
+// loadRows 按语句当前的条件和 value 的主键读取行，超过 AuditMaxRows 时返回 ErrAuditTooManyRows
+// value 中没有主键时（如 Model(&row).Update 的 value 为 nil）使用 Model 的主键，与 gorm 生成的 WHERE 一致
+func (d *Database) loadRows(s *schema.Schema, value interface{}) ([]reflect.Value, error) {
+	rows := reflect.New(reflect.SliceOf(s.ModelType))
+	db := d.db.Session(&gorm.Session{})
+	ctx := d.db.Statement.Context
+	conds := primaryKeyConditions(ctx, s, value)
+	if model := d.db.Statement.Model; len(conds) == 0 && model != nil {
+		conds = primaryKeyConditions(ctx, s, model)
+	}
+	if len(conds) > 0 {
+		keys := d.db.Session(&gorm.Session{NewDB: true}).Where(conds[0])
+		for _, c := range conds[1:] {
+			keys = keys.Or(c)
+		}
+		db = db.Where(keys)
+	}
+	if AuditMaxRows > 0 {
+		db = db.Limit(AuditMaxRows + 1)
+	}
+	if err := db.Find(rows.Interface()).Error; err != nil {
+		return nil, err
+	}
+	if AuditMaxRows > 0 && rows.Elem().Len() > AuditMaxRows {
+		return nil, fmt.Errorf("%w: more than %d rows of %s match", ErrAuditTooManyRows, AuditMaxRows, s.Table)
+	}
+	result := make([]reflect.Value, rows.Elem().Len())
+	for i := range result {
+		result[i] = rows.Elem().Index(i)
+	}
+	return result, nil
+}
+
+// primaryKeyConditions value 中主键不为零值的行的主键条件
+func primaryKeyConditions(ctx context.Context, s *schema.Schema, value interface{}) []map[string]interface{} {
+	var conds []map[string]interface{}
+	_ = eachStruct(value, func(rv reflect.Value) error {
+		if rv.Type() != s.ModelType {
+			return nil
+		}
+		cond := map[string]interface{}{}
+		for _, field := range s.PrimaryFields {
+			v, zero := field.ValueOf(ctx, rv)
+			if zero {
+				return nil
+			}
+			cond[field.DBName] = v
+		}
+		conds = append(conds, cond)
+		return nil
+	})
+	return conds
+}
+
+// auditRecords 根据变更前的行和变更后的值生成记录
+func (d *Database) auditRecords(op operation, s *schema.Schema, table string, cfg AuditConfig, value interface{}, before []reflect.Value) ([]AuditRecord, error) {
+	ctx := d.db.Statement.Context
+	actor, _ := ActorFromContext(ctx)
+	now := time.Now()
+	var records []AuditRecord
+	if op == opCreate {
+		err := eachStruct(value, func(rv reflect.Value) error {
+			if rv.Type() == s.ModelType {
+				records = append(records, newAuditRecord(ctx, op, s, table, cfg, actor, now, reflect.Value{}, rv))
+			}
+			return nil
+		})
+		return records, err
+	}
+	for _, b := range before {
+		var after reflect.Value
+		if op != opDelete {
+			row := reflect.New(s.ModelType)
+			cond := primaryKeyConditions(ctx, s, b.Addr().Interface())
+			if len(cond) == 0 {
+				continue
+			}
+			db := d.db.Session(&gorm.Session{NewDB: true}).Table(s.Table).Where(cond[0]).Limit(1).Find(row.Interface())
+			if db.Error != nil {
+				return nil, db.Error
+			}
+			if db.RowsAffected > 0 {
+				after = row.Elem()
+			}
+		}
+		// 软删除后的行仍然存在，按删除记录
+		records = append(records, newAuditRecord(ctx, op, s, table, cfg, actor, now, b, after))
+	}
+	return records, nil
+}
+
+func newAuditRecord(ctx context.Context, op operation, s *schema.Schema, table string, cfg AuditConfig, actor interface{}, now time.Time, before, after reflect.Value) AuditRecord {
+	r := AuditRecord{Table: table, Operation: op.String(), Actor: actor, Time: now}
+	row := after
+	if !row.IsValid() {
+		row = before
+	}
+	keys := make([]string, len(s.PrimaryFields))
+	for i, field := range s.PrimaryFields {
+		v, _ := field.ValueOf(ctx, row)
+		keys[i] = fmt.Sprint(v)
+	}
+	r.PrimaryKey = strings.Join(keys, ",")
+	if before.IsValid() {
+		r.Before = auditValues(ctx, s, cfg, before)
+	}
+	if after.IsValid() && op != opDelete {
+		r.After = auditValues(ctx, s, cfg, after)
+	}
+	for _, field := range s.Fields {
+		if field.DBName == "" || !auditedColumn(cfg, field.DBName) {
+			continue
+		}
+		b, bok := r.Before[field.DBName]
+		a, aok := r.After[field.DBName]
+		if bok != aok || !reflect.DeepEqual(b, a) {
+			r.Changes = append(r.Changes, field.DBName)
+		}
+	}
+	return r
+}
+
+func auditValues(ctx context.Context, s *schema.Schema, cfg AuditConfig, rv reflect.Value) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, field := range s.Fields {
+		if field.DBName == "" || !auditedColumn(cfg, field.DBName) {
+			continue
+		}
+		v, _ := field.ValueOf(ctx, rv)
+		values[field.DBName] = v
+	}
+	return values
+}
+
+func auditedColumn(cfg AuditConfig, column string) bool {
+	if len(cfg.Columns) == 0 {
+		return true
+	}
+	for _, c := range cfg.Columns {
+		if strings.EqualFold(c, column) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type auditRow struct {
+	ID   uint
+	Name string
+}
+
+func (auditRow) TableName() string  { return "audit_rows" }
+func (auditRow) TableAlias() string { return "" }
+func (auditRow) Audit() AuditConfig { return AuditConfig{Record: true} }
+
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (m *memoryAuditSink) WriteAudit(tx *Database, records []AuditRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, records...)
+	return nil
+}
+
+func TestAuditAliasedTable(t *testing.T) {
+	sink := &memoryAuditSink{}
+	RegisterAuditSink(sink)
+	defer RegisterAuditSink(nil)
+	db, b := newFakeDB(t, Mysql)
+	b.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"id", "name"}, [][]driver.Value{{int64(1), "old"}}, nil
+	}
+	err := NewDatabase(Mysql).Use(db).Table("audit_rows AS a").Model(&auditRow{ID: 1}).Update("name", "new").Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := b.count("SELECT * FROM `audit_rows` WHERE `id` = ?"); n != 1 {
+		t.Errorf("after re-read not found in %q", b.statements())
+	}
+	if len(sink.records) != 1 || sink.records[0].Table != "audit_rows" {
+		t.Errorf("records %+v", sink.records)
+	}
+}
+
+func TestAuditMaxRows(t *testing.T) {
+	RegisterAuditSink(&memoryAuditSink{})
+	defer RegisterAuditSink(nil)
+	old := AuditMaxRows
+	AuditMaxRows = 1
+	defer func() { AuditMaxRows = old }()
+	db, b := newFakeDB(t, Mysql)
+	b.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"id", "name"}, [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}}, nil
+	}
+	option := NewBuilderOption()
+	builder := NewConditionBuilder()
+	builder.AppendCondition("name", NotEqual, "")
+	option.AppendBuilder(builder)
+	err := NewDatabase(Mysql).Use(db).Model(&auditRow{}).Where(option).Update("name", "x").Error()
+	if !errors.Is(err, ErrAuditTooManyRows) {
+		t.Errorf("err = %v, want ErrAuditTooManyRows", err)
+	}
+	for _, s := range b.statements() {
+		if strings.HasPrefix(s, "UPDATE") {
+			t.Errorf("update executed: %q", b.statements())
+		}
+	}
+	if b.count("LIMIT 2") != 1 {
+		t.Errorf("before snapshot is not limited: %q", b.statements())
+	}
+}
+
+func TestAuditBeforeSnapshotUsesModel(t *testing.T) {
+	sink := &memoryAuditSink{}
+	RegisterAuditSink(sink)
+	defer RegisterAuditSink(nil)
+	tests := []struct {
+		name string
+		run  func(d *Database) error
+	}{
+		{
+			name: "update",
+			run: func(d *Database) error {
+				return d.Model(&auditRow{ID: 1}).Update("name", "new").Error()
+			},
+		},
+		{
+			name: "updates map",
+			run: func(d *Database) error {
+				return d.Model(&auditRow{ID: 1}).Updates(map[string]interface{}{"name": "new"}).Error()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink.records = nil
+			db, b := newFakeDB(t, Mysql)
+			var args [][]driver.NamedValue
+			b.query = func(query string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+				args = append(args, a)
+				return []string{"id", "name"}, [][]driver.Value{{int64(1), "old"}}, nil
+			}
+			if err := tt.run(NewDatabase(Mysql).Use(db)); err != nil {
+				t.Fatal(err)
+			}
+			stmts := b.statements()
+			want := "SELECT * FROM `audit_rows` WHERE `id` = ? LIMIT 10001"
+			if len(stmts) < 2 || stmts[1] != want {
+				t.Fatalf("statements %q, want the before snapshot %q", stmts, want)
+			}
+			if len(args) == 0 || len(args[0]) != 1 || args[0][0].Value != int64(1) {
+				t.Errorf("before snapshot args %v, want [1]", args)
+			}
+			if len(sink.records) != 1 || sink.records[0].Before["name"] != "old" {
+				t.Errorf("records %+v", sink.records)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package dac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBufferFull 写入缓冲区已满
+	ErrBufferFull = errors.New("batch writer buffer is full")
+	// ErrWriterClosed 写入器已关闭
+	ErrWriterClosed = errors.New("batch writer is closed")
+)
+
+// FlushError 刷新失败，Rows 为本次未写入的行
+type FlushError struct {
+	Err  error
+	Rows []interface{}
+}
+
+func (e *FlushError) Error() string {
+	return fmt.Sprintf("flush %d rows: %v", len(e.Rows), e.Err)
+}
+
+func (e *FlushError) Unwrap() error {
+	return e.Err
+}
+
+// BatchWriterOption 批量写入配置
+type BatchWriterOption struct {
+	BatchSize     int                   // 缓冲达到该条数时刷新，默认 DefaultBatchSize
+	FlushInterval time.Duration         // 定时刷新间隔，默认 1 秒
+	BufferSize    int                   // 缓冲区容量，写满后 Write 阻塞，默认 BatchSize 的 4 倍
+	OnError       func(err *FlushError) // 刷新失败回调，可用于重试或落盘失败的行
+}
+
+// BatchWriter 批量写入器，适用于向 ClickHouse 高频写入事件
+// 行先写入缓冲区，按条数或时间间隔刷新，每次刷新只执行一条多行 INSERT
+type BatchWriter struct {
+	base  *Database // 创建写入器的实例，用于填充租户等字段
+	db    *gorm.DB
+	table string
+	opt   BatchWriterOption
+
+	rows     chan interface{}
+	flushReq chan chan error
+	done     chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+	errs   []error // 尚未通过 Flush 或 Close 返回的刷新错误，只在 run 中访问
+}
+
+// NewBatchWriter 创建批量写入器，使用完毕后必须调用 Close
+func (d *Database) NewBatchWriter(table string, opt BatchWriterOption) *BatchWriter {
+	tx := d.getInstance()
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = DefaultBatchSize
+	}
+	if opt.FlushInterval <= 0 {
+		opt.FlushInterval = time.Second
+	}
+	if opt.BufferSize <= 0 {
+		opt.BufferSize = opt.BatchSize * 4
+	}
+	w := &BatchWriter{
+		base:     tx.clone(nil),
+		db:       tx.db.Session(&gorm.Session{NewDB: true}),
+		table:    table,
+		opt:      opt,
+		rows:     make(chan interface{}, opt.BufferSize),
+		flushReq: make(chan chan error),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write 写入一行，缓冲区已满时阻塞直到有空间或 ctx 结束
+func (w *BatchWriter) Write(ctx context.Context, row interface{}) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrWriterClosed
+	}
+	select {
+	case w.rows <- row:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryWrite 写入一行，缓冲区已满时立即返回 ErrBufferFull
+func (w *BatchWriter) TryWrite(row interface{}) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrWriterClosed
+	}
+	select {
+	case w.rows <- row:
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+// Pending 缓冲区中等待刷新的行数
+func (w *BatchWriter) Pending() int {
+	return len(w.rows)
+}
+
+// Flush 立即刷新缓冲区中的行，返回上次 Flush 之后所有失败的刷新错误，包括定时和按条数触发的刷新
+func (w *BatchWriter) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case w.flushReq <- reply:
+	case <-w.done:
+		return ErrWriterClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 停止写入，刷新缓冲区中剩余的行后返回尚未被 Flush 返回的刷新错误
+func (w *BatchWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.rows)
+	}
+	w.mu.Unlock()
+	<-w.done
+	return w.takeErrs()
+}
+
+func (w *BatchWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opt.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]interface{}, 0, w.opt.BatchSize)
+	flush := func() error {
+		err := w.flush(batch)
+		batch = make([]interface{}, 0, w.opt.BatchSize)
+		return err
+	}
+	for {
+		select {
+		case row, ok := <-w.rows:
+			if !ok {
+				if len(batch) > 0 {
+					w.record(flush())
+				}
+				return
+			}
+			batch = append(batch, row)
+			if len(batch) >= w.opt.BatchSize {
+				w.record(flush())
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.record(flush())
+			}
+		case reply := <-w.flushReq:
+			// 先取出缓冲区中已写入的行
+			for drained := false; !drained; {
+				select {
+				case row, ok := <-w.rows:
+					if !ok {
+						drained = true
+						break
+					}
+					batch = append(batch, row)
+				default:
+					drained = true
+				}
+			}
+			for start := 0; start < len(batch); start += w.opt.BatchSize {
+				end := start + w.opt.BatchSize
+				if end > len(batch) {
+					end = len(batch)
+				}
+				w.record(w.flush(batch[start:end]))
+			}
+			batch = make([]interface{}, 0, w.opt.BatchSize)
+			reply <- w.takeErrs()
+		}
+	}
+}
+
+// record 保存刷新错误，直到调用方通过 Flush 或 Close 取走
+func (w *BatchWriter) record(err error) {
+	if err != nil {
+		w.errs = append(w.errs, err)
+	}
+}
+
+// takeErrs 合并并清空已保存的刷新错误
+func (w *BatchWriter) takeErrs() error {
+	err := errors.Join(w.errs...)
+	w.errs = nil
+	return err
+}
+
+// flush 将一批行组装为同类型切片，执行一条多行 INSERT
+func (w *BatchWriter) flush(batch []interface{}) error {
+	rowType := reflect.TypeOf(batch[0])
+	rows := reflect.MakeSlice(reflect.SliceOf(rowType), 0, len(batch))
+	for _, row := range batch {
+		if reflect.TypeOf(row) != rowType {
+			return w.fail(fmt.Errorf("batch writer expects rows of type %s, got %T", rowType, row), batch)
+		}
+		rows = reflect.Append(rows, reflect.ValueOf(row))
+	}
+	tx := w.base.clone(w.db.Table(w.table))
+	defer tx.observe("batch_write", rows.Interface())()
+	if err := tx.prepare(opCreate, rows.Interface()); err != nil {
+		return w.fail(err, batch)
+	}
+	tx.useSourceDB(tx.db.CreateInBatches(rows.Interface(), len(batch)))
+	if err := tx.db.Error; err != nil {
+		return w.fail(err, batch)
+	}
+	tx.invalidate(rows.Interface())
+	return nil
+}
+
+func (w *BatchWriter) fail(err error, batch []interface{}) error {
+	flushErr := &FlushError{Err: err, Rows: batch}
+	if w.opt.OnError != nil {
+		w.opt.OnError(flushErr)
+	}
+	return flushErr
+}
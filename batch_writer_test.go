@@ -0,0 +1,93 @@
+package dac
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type batchEvent struct {
+	ID   uint
+	Name string
+}
+
+func TestBatchWriterKeepsErrors(t *testing.T) {
+	for _, dbType := range []DBType{Mysql, Clickhouse} {
+		t.Run(string(dbType), func(t *testing.T) {
+			testBatchWriterKeepsErrors(t, dbType)
+		})
+	}
+}
+
+func testBatchWriterKeepsErrors(t *testing.T, dbType DBType) {
+	db, b := newFakeDB(t, dbType)
+	var mu sync.Mutex
+	calls := 0
+	b.exec = func(query string, args []driver.NamedValue) (int64, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			return 0, errors.New("first insert failed")
+		}
+		return 1, nil
+	}
+	w := NewDatabase(dbType).Use(db).NewBatchWriter("batch_events", BatchWriterOption{BatchSize: 1})
+	ctx := context.Background()
+	for i := uint(1); i <= 3; i++ {
+		if err := w.Write(ctx, batchEvent{ID: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := w.Flush(ctx)
+	var flushErr *FlushError
+	if !errors.As(err, &flushErr) || !strings.Contains(err.Error(), "first insert failed") {
+		t.Fatalf("Flush() = %v, want the first flush error", err)
+	}
+	if err := w.Flush(ctx); err != nil {
+		t.Errorf("second Flush() = %v, want nil", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	if n := b.count("INSERT INTO"); n != 3 {
+		t.Errorf("inserts = %d, want 3", n)
+	}
+}
+
+func TestBatchWriterClickhouseMultiRowInsert(t *testing.T) {
+	db, b := newFakeDB(t, Clickhouse)
+	var mu sync.Mutex
+	var batches [][]driver.NamedValue
+	b.exec = func(query string, args []driver.NamedValue) (int64, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, args)
+		return int64(len(args) / 2), nil
+	}
+	w := NewDatabase(Clickhouse).Use(db).NewBatchWriter("batch_events", BatchWriterOption{BatchSize: 2, FlushInterval: time.Hour})
+	for i := uint(1); i <= 4; i++ {
+		if err := w.TryWrite(batchEvent{ID: i, Name: "e"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stmts := b.statements()
+	if len(stmts) != 2 {
+		t.Fatalf("statements %q, want 2 inserts", stmts)
+	}
+	for i, s := range stmts {
+		if want := "INSERT INTO `batch_events` (`name`,`id`) VALUES (?,?),(?,?)"; s != want {
+			t.Errorf("insert %d = %q, want %q", i, s, want)
+		}
+		if len(batches[i]) != 4 {
+			t.Errorf("insert %d args = %v", i, batches[i])
+		}
+	}
+}
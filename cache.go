@@ -0,0 +1,327 @@
+package dac
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultCacheSize 默认内存缓存的条数
+const DefaultCacheSize = 1000
+
+// CacheBackend 查询缓存的存储，值为查询结果使用 encoding/gob 序列化后的数据
+type CacheBackend interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration, tags []string)
+	// InvalidateTags 删除带有任一标签的缓存
+	InvalidateTags(tags ...string)
+}
+
+var (
+	cacheMu      sync.RWMutex
+	cacheBackend CacheBackend = NewLRUCache(DefaultCacheSize)
+	cacheFlight  flightGroup
+)
+
+// RegisterCacheBackend 设置查询缓存的存储，默认为进程内的 LRU，多实例部署时写操作只能使本实例的缓存失效
+// 为 nil 时关闭缓存，Cache 不生效
+func RegisterCacheBackend(backend CacheBackend) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cacheBackend = backend
+}
+
+func getCacheBackend() CacheBackend {
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	return cacheBackend
+}
+
+// InvalidateCache 删除带有任一标签的缓存，用于 Exec、Raw 等无法自动失效的写操作
+func InvalidateCache(tags ...string) {
+	if backend := getCacheBackend(); backend != nil && len(tags) > 0 {
+		backend.InvalidateTags(tags...)
+	}
+}
+
+// Cache 缓存 Find、First、Last、Scan、Count、Pluck 的结果，key 为数据库类型、SQL 和参数
+// 缓存自动带有查询的表名作为标签，通过 Create、Save、Updates、Update、Delete 等写入该表时失效；
+// 连接查询的其他表需要通过 tags 指定；事务中的查询不使用缓存。
+// 结果使用 encoding/gob 序列化，按字段名编码，不受 json 标签影响，只保留导出的字段；
+// map[string]interface{} 中 time.Time 以外的非基本类型无法序列化，这样的结果不缓存
+func (d *Database) Cache(ttl time.Duration, tags ...string) *Database {
+	tx := d.getInstance()
+	tx.cacheTTL = ttl
+	tx.cacheTags = append(tx.cacheTags, tags...)
+	return tx
+}
+
+// tableTag 语句的表名，用于缓存标签、租户表查找等，Table 设置了别名时取原表名
+func (d *Database) tableTag(value interface{}) string {
+	s, table := d.target(value)
+	if s != nil {
+		return s.Table
+	}
+	if expr := d.db.Statement.TableExpr; expr != nil && !strings.HasPrefix(strings.TrimSpace(expr.SQL), "(") {
+		if fields := strings.Fields(expr.SQL); len(fields) > 0 {
+			return strings.Trim(fields[0], "`\"")
+		}
+	}
+	return table
+}
+
+// cacheKey 缓存的 key
+func cacheKey(dbType DBType, sql string, vars []interface{}) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s", dbType, sql)
+	for _, v := range vars {
+		fmt.Fprintf(h, "\x00%T:%v", v, v)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// query 执行查询，设置了 Cache 时先读取缓存，相同 key 的并发查询只执行一次
+func (d *Database) query(out interface{}, run func(db *gorm.DB) *gorm.DB) *Database {
+	backend := getCacheBackend()
+	// 事务中可能读到未提交的数据，不读写缓存
+	if d.cacheTTL <= 0 || backend == nil || d.err != nil || d.inTransaction() {
+		return d.retryQuery(run)
+	}
+	dry := run(d.db.Session(&gorm.Session{DryRun: true}))
+	if dry.Error != nil {
+		return d.retryQuery(run)
+	}
+	key := cacheKey(d.DBType, dry.Statement.SQL.String(), dry.Statement.Vars)
+	if data, ok := backend.Get(key); ok && decodeResult(data, out) == nil {
+		return d
+	}
+	tags := append([]string{d.tableTag(out)}, d.cacheTags...)
+	executed := false
+	data, err, _ := cacheFlight.Do(key, func() ([]byte, error) {
+		executed = true
+		d.retryQuery(run)
+		if d.db.Error != nil {
+			return nil, d.db.Error
+		}
+		data, err := encodeResult(out)
+		if err != nil {
+			// 无法序列化的结果不缓存
+			return nil, nil
+		}
+		backend.Set(key, data, d.cacheTTL, tags)
+		return data, nil
+	})
+	if executed {
+		return d
+	}
+	if err != nil {
+		d.err = err
+		return d
+	}
+	if data == nil || decodeResult(data, out) != nil {
+		return d.retryQuery(run)
+	}
+	return d
+}
+
+func init() {
+	// map[string]interface{} 的结果中常见的时间类型
+	gob.Register(time.Time{})
+}
+
+// encodeResult 序列化查询结果
+func encodeResult(out interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeResult 反序列化查询结果
+func decodeResult(data []byte, out interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(out)
+}
+
+// invalidate 写操作成功后使表的缓存失效，在 Transaction 中时等到提交后再失效
+func (d *Database) invalidate(value interface{}) {
+	if d.err != nil || d.db.Error != nil {
+		return
+	}
+	tag := d.tableTag(value)
+	if tag == "" {
+		return
+	}
+	if d.pending != nil {
+		d.pending.add(tag)
+		return
+	}
+	InvalidateCache(tag)
+}
+
+// pendingInvalidation 事务中待失效的缓存标签
+type pendingInvalidation struct {
+	mu   sync.Mutex
+	tags []string
+}
+
+func (p *pendingInvalidation) add(tag string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tags = append(p.tags, tag)
+}
+
+// flush 事务提交后使缓存失效
+func (p *pendingInvalidation) flush() {
+	p.mu.Lock()
+	tags := p.tags
+	p.tags = nil
+	p.mu.Unlock()
+	InvalidateCache(tags...)
+}
+
+// flightGroup 合并相同 key 的并发调用
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg   sync.WaitGroup
+	val  []byte
+	err  error
+	dups int
+}
+
+// Do 执行 fn，执行期间相同 key 的调用等待并共享结果
+func (g *flightGroup) Do(key string, fn func() ([]byte, error)) ([]byte, error, bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &flightCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err, c.dups > 0
+}
+
+// LRUCache 进程内的 LRU 缓存
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	tags     map[string]map[string]struct{}
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+	tags    []string
+}
+
+// NewLRUCache 创建最多保存 capacity 条的 LRU 缓存
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity <= 0 {
+		capacity = DefaultCacheSize
+	}
+	return &LRUCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		tags:     make(map[string]map[string]struct{}),
+	}
+}
+
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *LRUCache) Set(key string, value []byte, ttl time.Duration, tags []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	entry := &lruEntry{key: key, value: value, expires: time.Now().Add(ttl), tags: tags}
+	c.items[key] = c.ll.PushFront(entry)
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+	for c.ll.Len() > c.capacity {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *LRUCache) InvalidateTags(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if el, ok := c.items[key]; ok {
+				c.remove(el)
+			}
+		}
+		delete(c.tags, tag)
+	}
+}
+
+// Len 缓存的条数
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *LRUCache) remove(el *list.Element) {
+	entry := el.Value.(*lruEntry)
+	c.ll.Remove(el)
+	delete(c.items, entry.key)
+	for _, tag := range entry.tags {
+		if keys := c.tags[tag]; keys != nil {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+type cachedRow struct {
+	ID     uint
+	Name   string `json:"-"`
+	Secret string `json:"renamed,omitempty"`
+}
+
+// newCacheTestDB 使用独立的缓存和 fakeBackend，查询返回一行
+func newCacheTestDB(t *testing.T) (*Database, *fakeBackend) {
+	t.Helper()
+	RegisterCacheBackend(NewLRUCache(100))
+	t.Cleanup(func() { RegisterCacheBackend(NewLRUCache(DefaultCacheSize)) })
+	db, b := newFakeDB(t, Mysql)
+	b.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"id", "name", "secret"}, [][]driver.Value{{int64(1), "a", "s"}}, nil
+	}
+	return NewDatabase(Mysql).Use(db), b
+}
+
+func TestCache(t *testing.T) {
+	errRollback := errors.New("rollback")
+	tests := []struct {
+		name        string
+		run         func(t *testing.T, d *Database)
+		wantQueries int
+	}{
+		{
+			name:        "hit",
+			run:         func(t *testing.T, d *Database) {},
+			wantQueries: 1,
+		},
+		{
+			name: "invalidated by write",
+			run: func(t *testing.T, d *Database) {
+				if err := d.clone(d.db).Model(&cachedRow{ID: 1}).Update("name", "b").Error(); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantQueries: 2,
+		},
+		{
+			name: "invalidated after commit",
+			run: func(t *testing.T, d *Database) {
+				err := d.clone(d.db).Transaction(func(tx *Database) error {
+					if err := tx.Model(&cachedRow{ID: 1}).Update("name", "b").Error(); err != nil {
+						return err
+					}
+					// 提交前其他连接仍读取缓存
+					assertCachedFind(t, d.clone(d.db))
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantQueries: 2,
+		},
+		{
+			name: "kept after rollback",
+			run: func(t *testing.T, d *Database) {
+				err := d.clone(d.db).Transaction(func(tx *Database) error {
+					if err := tx.Model(&cachedRow{ID: 1}).Update("name", "b").Error(); err != nil {
+						return err
+					}
+					return errRollback
+				})
+				if !errors.Is(err, errRollback) {
+					t.Fatalf("err = %v", err)
+				}
+			},
+			wantQueries: 1,
+		},
+		{
+			name: "skipped in transaction",
+			run: func(t *testing.T, d *Database) {
+				err := d.clone(d.db).Transaction(func(tx *Database) error {
+					var rows []cachedRow
+					return tx.Cache(time.Minute).Find(&rows).Error()
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantQueries: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newCacheTestDB(t)
+			assertCachedFind(t, d.clone(d.db))
+			tt.run(t, d)
+			assertCachedFind(t, d.clone(d.db))
+			if n := b.count("SELECT"); n != tt.wantQueries {
+				t.Errorf("queries = %d, want %d: %q", n, tt.wantQueries, b.statements())
+			}
+		})
+	}
+}
+
+// assertCachedFind 使用缓存查询，结果必须包含 json 标签忽略或重命名的字段
+func assertCachedFind(t *testing.T, d *Database) {
+	t.Helper()
+	var rows []cachedRow
+	if err := d.Cache(time.Minute).Find(&rows).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Name != "a" || rows[0].Secret != "s" {
+		t.Errorf("rows = %+v", rows)
+	}
+}
@@ -2,6 +2,8 @@ package dac
 
 import (
 	"fmt"
+	"strings"
+
 	"gorm.io/gorm"
 )
 
@@ -41,11 +43,152 @@ func (co *ClickhouseOperator) BuildQuery(condition Condition, qf *QueryFilter) {
 		co.LessThan(condition, qf)
 	case In:
 		co.In(condition, qf)
+	case NotIn:
+		co.NotIn(condition, qf)
+	case IsNull:
+		co.IsNull(condition, qf)
+	case IsNotNull:
+		co.IsNotNull(condition, qf)
+	case Between:
+		co.Between(condition, qf)
+	case NotBetween:
+		co.NotBetween(condition, qf)
+	case Exists:
+		co.Exists(condition, qf)
+	case NotExists:
+		co.NotExists(condition, qf)
+	case Match:
+		co.Match(condition, qf)
+	case JSONContains:
+		co.JSONContains(condition, qf)
+	case JSONHasKey:
+		co.JSONHasKey(condition, qf)
+	case ArrayContains:
+		co.ArrayContains(condition, qf)
+	case ArrayContainsAll:
+		co.ArrayContainsAll(condition, qf)
+	case ArrayOverlaps:
+		co.ArrayOverlaps(condition, qf)
+	case ArrayLength:
+		co.ArrayLength(condition, qf)
+	case Like:
+		co.Like(condition, qf)
+	case NotLike:
+		co.NotLike(condition, qf)
+	case Contains, StartsWith, EndsWith:
+		co.Contains(condition, qf)
+	case ILike:
+		co.ILike(condition, qf)
+	case IEqual:
+		co.IEqual(condition, qf)
+	default:
+		qf.Err = fmt.Errorf("operator %s is not supported on %s", condition.Operator, Clickhouse)
+	}
+}
+
+// Match 全文检索，单个字段匹配单个分词时使用 hasToken，可以命中 tokenbf_v1 索引；
+// 否则按空白拆分为多个词，任一字段包含任一词即匹配
+func (co *ClickhouseOperator) Match(condition Condition, qf *QueryFilter) {
+	q, _ := fullTextValue(condition.Value)
+	columns := fullTextColumns(condition.Key)
+	words := strings.Fields(q.Text)
+	if len(columns) == 1 && len(words) == 1 && isToken(words[0]) {
+		qf.And(fmt.Sprintf("hasToken(%s, ?)", columns[0]), words[0])
+		return
+	}
+	if len(words) == 0 {
+		words = []string{q.Text}
 	}
+	needles := strings.TrimSuffix(strings.Repeat("?,", len(words)), ",")
+	parts := make([]string, len(columns))
+	var args []interface{}
+	for i, c := range columns {
+		parts[i] = fmt.Sprintf("multiSearchAny(%s, [%s])", c, needles)
+		for _, w := range words {
+			args = append(args, w)
+		}
+	}
+	query := strings.Join(parts, " OR ")
+	if len(parts) > 1 {
+		query = "(" + query + ")"
+	}
+	qf.And(query, args...)
+}
+
+// JSONContains ClickHouse 不支持，ConditionBuilder.Build 会返回 ErrUnsupported
+func (co *ClickhouseOperator) JSONContains(condition Condition, qf *QueryFilter) {
+	qf.Err = fmt.Errorf("%w: %s on %s", ErrUnsupported, JSONContains, Clickhouse)
+}
+
+// JSONHasKey 字段为保存 JSON 文本的 String 列
+func (co *ClickhouseOperator) JSONHasKey(condition Condition, qf *QueryFilter) {
+	p, _ := parseJSONPath(condition.Key)
+	qf.And(fmt.Sprintf("JSONHas(%s, %s)", p.column, p.clickhouseArgs()))
+}
+
+func (co *ClickhouseOperator) ArrayContains(condition Condition, qf *QueryFilter) {
+	qf.And(fmt.Sprintf("has(%s, ?)", condition.Key), condition.Value)
+}
+func (co *ClickhouseOperator) ArrayContainsAll(condition Condition, qf *QueryFilter) {
+	elements := condition.Value.([]interface{})
+	qf.And(fmt.Sprintf("hasAll(%s, [%s])", condition.Key, placeholders(len(elements))), elements...)
+}
+func (co *ClickhouseOperator) ArrayOverlaps(condition Condition, qf *QueryFilter) {
+	elements := condition.Value.([]interface{})
+	qf.And(fmt.Sprintf("hasAny(%s, [%s])", condition.Key, placeholders(len(elements))), elements...)
+}
+func (co *ClickhouseOperator) ArrayLength(condition Condition, qf *QueryFilter) {
+	qf.And(fmt.Sprintf("length(%s) = ?", condition.Key), condition.Value)
+}
+
+func (co *ClickhouseOperator) Like(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" LIKE ?", condition.Value)
+}
+func (co *ClickhouseOperator) NotLike(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" NOT LIKE ?", condition.Value)
+}
+
+// Contains 同时用于 StartsWith、EndsWith，值已转义并添加通配符
+func (co *ClickhouseOperator) Contains(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" LIKE ?", condition.Value)
+}
+
+// ILike 值未转义，按字面查找子串
+func (co *ClickhouseOperator) ILike(condition Condition, qf *QueryFilter) {
+	qf.And(fmt.Sprintf("positionCaseInsensitive(%s, ?) > 0", condition.Key), condition.Value)
+}
+func (co *ClickhouseOperator) IEqual(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" ILIKE ?", condition.Value)
+}
+
+func (co *ClickhouseOperator) IsNull(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key + " IS NULL")
+}
+func (co *ClickhouseOperator) IsNotNull(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key + " IS NOT NULL")
+}
+func (co *ClickhouseOperator) Between(condition Condition, qf *QueryFilter) {
+	low, high, _ := betweenValues(condition.Value)
+	qf.And(condition.Key+" BETWEEN ? AND ?", low, high)
+}
+func (co *ClickhouseOperator) NotBetween(condition Condition, qf *QueryFilter) {
+	low, high, _ := betweenValues(condition.Value)
+	qf.And(condition.Key+" NOT BETWEEN ? AND ?", low, high)
+}
+
+// Exists ClickHouse 的 EXISTS 只支持非关联子查询
+func (co *ClickhouseOperator) Exists(condition Condition, qf *QueryFilter) {
+	qf.And("EXISTS (?)", condition.Value)
+}
+func (co *ClickhouseOperator) NotExists(condition Condition, qf *QueryFilter) {
+	qf.And("NOT EXISTS (?)", condition.Value)
 }
 func (co *ClickhouseOperator) In(condition Condition, qf *QueryFilter) {
 	qf.And(condition.Key+" in (?)", condition.Value)
 }
+func (co *ClickhouseOperator) NotIn(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" not in (?)", condition.Value)
+}
 func (co *ClickhouseOperator) GreaterThanOrEqual(condition Condition, qf *QueryFilter) {
 	qf.And(condition.Key+" >= ?", condition.Value)
 }
@@ -66,10 +209,10 @@ func (co *ClickhouseOperator) Blank(condition Condition, qf *QueryFilter) {
 }
 
 func (co *ClickhouseOperator) NotEqual(condition Condition, qf *QueryFilter) {
-	qf.And(fmt.Sprintf("%s <> ? ", condition.Field), condition.Value)
+	qf.And(fmt.Sprintf("%s <> ? ", condition.Key), condition.Value)
 }
 
 // Equals 等于
 func (co *ClickhouseOperator) Equals(condition Condition, qf *QueryFilter) {
-	qf.And(fmt.Sprintf("%s = ? ", condition.Field), condition.Value)
+	qf.And(fmt.Sprintf("%s = ? ", condition.Key), condition.Value)
 }
@@ -2,6 +2,8 @@ package dac
 
 import (
 	"fmt"
+	"reflect"
+
 	"gorm.io/gorm"
 )
 
@@ -25,11 +27,25 @@ const (
 	Like               Operator = "like"
 	In                 Operator = "in"
 	NotIn              Operator = "notIn"
-	IsNull             Operator = "isNull"
-	IsNotNull          Operator = "isNotNull"
+	IsNull             Operator = "isNull"    // 不使用值
+	IsNotNull          Operator = "isNotNull" // 不使用值
 	NotLike            Operator = "notLike"
-	NotBetween         Operator = "notBetween"
-	Between            Operator = "between"
+	NotBetween         Operator = "notBetween" // 值为两个元素的切片或数组
+	Between            Operator = "between"    // 值为两个元素的切片或数组，包含两端
+	Exists             Operator = "exists"
+	NotExists          Operator = "notExists"
+	Match              Operator = "match"            // 全文检索，Field 可以是逗号分隔的多个字段，值为字符串或 FullText
+	JSONContains       Operator = "jsonContains"     // JSON 字段包含值，值会序列化为 JSON，Field 可以带路径
+	JSONHasKey         Operator = "jsonHasKey"       // JSON 字段存在路径，Field 为 column->path，不使用值
+	ArrayContains      Operator = "arrayContains"    // 数组包含元素，MySQL 中为 JSON 数组
+	ArrayContainsAll   Operator = "arrayContainsAll" // 数组包含切片中的所有元素
+	ArrayOverlaps      Operator = "arrayOverlaps"    // 数组包含切片中的任一元素
+	ArrayLength        Operator = "arrayLength"      // 数组长度等于值
+	Contains           Operator = "contains"         // 包含子串，值中的 %、_ 按字面匹配
+	StartsWith         Operator = "startsWith"       // 以值开头
+	EndsWith           Operator = "endsWith"         // 以值结尾
+	ILike              Operator = "iLike"            // 忽略大小写包含子串
+	IEqual             Operator = "iEqual"           // 忽略大小写相等
 )
 
 // OperatorI 定义了操作符接口
@@ -92,6 +108,7 @@ func (b *BuilderOption) AppendBuilder(builder *ConditionBuilder) *BuilderOption
 type ConditionBuilder struct {
 	conditions []Condition
 	args       []interface{}
+	err        error
 }
 
 func NewConditionBuilder() *ConditionBuilder {
@@ -118,7 +135,7 @@ func (cb *ConditionBuilder) setJoiner(joiner JoinerType) *ConditionBuilder {
 
 // AddCondition 方法用于添加条件
 func (cb *ConditionBuilder) AddCondition(condition *Condition) *ConditionBuilder {
-	if condition.Field == "" {
+	if condition.Field == "" && condition.Operator != Exists && condition.Operator != NotExists {
 		return cb
 	}
 	cb.conditions = append(cb.conditions, *condition)
@@ -146,14 +163,51 @@ func (cb *ConditionBuilder) Build(dbType DBType) (string, []interface{}) {
 	if cb.conditions == nil {
 		return "", nil
 	}
+	cb.err = nil
 	qfCondition := &QueryFilter{}
 	for _, condition := range cb.conditions {
 		//condition.Key = parseField(condition.Field, dbType)
-		//value 可能为数组什么的
-		//condition.Value = parseValue(condition.Value, dbType)
+		if (condition.Operator == Exists || condition.Operator == NotExists) && !isSubQuery(condition.Value) {
+			cb.err = fmt.Errorf("operator %s requires a subquery value, got %T", condition.Operator, condition.Value)
+			return "", nil
+		}
+		if condition.Operator == Between || condition.Operator == NotBetween {
+			if _, _, err := betweenValues(condition.Value); err != nil {
+				cb.err = err
+				return "", nil
+			}
+		}
+		if condition.Operator == Match {
+			if _, err := fullTextValue(condition.Value); err != nil {
+				cb.err = err
+				return "", nil
+			}
+		}
+		value, err := parseValue(condition.Value, dbType)
+		if err != nil {
+			cb.err = err
+			return "", nil
+		}
+		condition.Value = value
 		condition.Key = condition.Field
+		if err := buildJSONCondition(dbType, &condition); err != nil {
+			cb.err = err
+			return "", nil
+		}
+		if err := buildArrayCondition(dbType, &condition); err != nil {
+			cb.err = err
+			return "", nil
+		}
+		if err := buildStringCondition(dbType, &condition); err != nil {
+			cb.err = err
+			return "", nil
+		}
 		qf := &QueryFilter{}
 		GetOperatorI(dbType).BuildQuery(condition, qf)
+		if qf.Err != nil {
+			cb.err = qf.Err
+			return "", nil
+		}
 		if condition.Joiner == "" {
 			condition.Joiner = And
 		}
@@ -170,10 +224,28 @@ func (cb *ConditionBuilder) Build(dbType DBType) (string, []interface{}) {
 	return fmt.Sprintf("%s", qfCondition.Query), qfCondition.Args
 }
 
+// betweenValues 取出 Between、NotBetween 的上下界
+func betweenValues(value interface{}) (interface{}, interface{}, error) {
+	v := reflect.ValueOf(value)
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Len() != 2 {
+		return nil, nil, fmt.Errorf("between requires a value with 2 elements, got %T", value)
+	}
+	return v.Index(0).Interface(), v.Index(1).Interface(), nil
+}
+
+// Error 获取最近一次 Build 产生的错误
+func (cb *ConditionBuilder) Error() error {
+	return cb.err
+}
+
 // 将条件添加到Where查询中
 func buildWhereConditions(db *gorm.DB, dbType DBType, buildOption *BuilderOption) *gorm.DB {
 	for _, v := range buildOption.builders {
 		query, args := v.Build(dbType)
+		if err := v.Error(); err != nil {
+			db.AddError(err)
+			continue
+		}
 		if query != "" {
 			db = db.Where(query, args...)
 		}
@@ -184,6 +256,9 @@ func buildWhereConditions(db *gorm.DB, dbType DBType, buildOption *BuilderOption
 // 将条件添加到查询中
 func addHavingConditions(db *gorm.DB, dbType DBType, builder *ConditionBuilder) error {
 	query, args := builder.Build(dbType)
+	if err := builder.Error(); err != nil {
+		return err
+	}
 	*db = *db.Having(query, args...)
 	return nil
 }
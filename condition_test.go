@@ -1,6 +1,7 @@
 package dac
 
 import (
+	"database/sql/driver"
 	"fmt"
 	"testing"
 )
@@ -27,10 +28,143 @@ func TestCreate(t *testing.T) {
 	builder.AppendCondition("id", Equal, 1)
 	option.AppendBuilder(builder)
 	var cs []CountList
-	err := NewDatabase(Mysql).Use(nil).Where(option).Select("id", Count(1).As("count")).
+	db, _ := newFakeDB(t, Mysql)
+	err := NewDatabase(Mysql).Use(db).Where(option).Select("id", Count(1, "count")).
 		Group("id").Find(&cs).Error()
 	if err != nil {
 		t.Logf(err.Error())
 	}
 	fmt.Println(cs)
 }
+
+// conditionSQL 在 users 表上执行查询，返回执行的语句和参数
+func conditionSQL(t *testing.T, dbType DBType, builder *ConditionBuilder) (string, []driver.NamedValue, error) {
+	t.Helper()
+	db, b := newFakeDB(t, dbType)
+	var args []driver.NamedValue
+	b.query = func(query string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		args = a
+		return nil, nil, nil
+	}
+	var rows []map[string]interface{}
+	err := NewDatabase(dbType).Use(db).Table("users").
+		Where(NewBuilderOption().AppendBuilder(builder)).Find(&rows).Error()
+	stmts := b.statements()
+	if len(stmts) == 0 {
+		return "", nil, err
+	}
+	return stmts[len(stmts)-1], args, err
+}
+
+func TestConditionOperators(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator Operator
+		value    interface{}
+		want     string
+		args     int
+	}{
+		{"is null", IsNull, nil, "age IS NULL", 0},
+		{"is not null", IsNotNull, nil, "age IS NOT NULL", 0},
+		{"between", Between, []int{18, 30}, "age BETWEEN ? AND ?", 2},
+		{"not between", NotBetween, [2]int{18, 30}, "age NOT BETWEEN ? AND ?", 2},
+	}
+	for _, dbType := range []DBType{Mysql, Postgres, Clickhouse} {
+		for _, tt := range tests {
+			t.Run(string(dbType)+"/"+tt.name, func(t *testing.T) {
+				query, args := NewConditionBuilder().AppendCondition("age", tt.operator, tt.value).Build(dbType)
+				if query != tt.want || len(args) != tt.args {
+					t.Errorf("Build() = %q %v, want %q with %d args", query, args, tt.want, tt.args)
+				}
+			})
+		}
+	}
+}
+
+func TestConditionOperatorErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator Operator
+		value    interface{}
+	}{
+		{"between needs two values", Between, []int{18}},
+		{"between needs a slice", NotBetween, 18},
+		{"exists needs a subquery", Exists, "SELECT 1"},
+		{"unknown operator", Operator("near"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewConditionBuilder().AddCondition(&Condition{Field: "age", Operator: tt.operator, Value: tt.value})
+			if query, _ := cb.Build(Mysql); query != "" || cb.Error() == nil {
+				t.Errorf("Build() = %q, %v, want an error", query, cb.Error())
+			}
+		})
+	}
+}
+
+func TestSubQueryConditions(t *testing.T) {
+	orders := func() *SubQuery {
+		return NewSubQuery("orders", "user_id").
+			Where(NewConditionBuilder().AppendCondition("status", Equal, "paid"))
+	}
+	tests := []struct {
+		name   string
+		dbType DBType
+		build  func() *ConditionBuilder
+		want   string
+	}{
+		{
+			name:   "in subquery",
+			dbType: Mysql,
+			build: func() *ConditionBuilder {
+				return NewConditionBuilder().AppendCondition("id", In, orders())
+			},
+			want: "SELECT * FROM `users` WHERE id in (SELECT user_id FROM orders WHERE status = ?)",
+		},
+		{
+			name:   "exists",
+			dbType: Postgres,
+			build: func() *ConditionBuilder {
+				return NewConditionBuilder().
+					AppendCondition("name", Equal, "a").
+					AddCondition(&Condition{Operator: Exists, Value: orders()})
+			},
+			want: `SELECT * FROM "users" WHERE name = $1 AND EXISTS (SELECT user_id FROM orders WHERE status = $2)`,
+		},
+		{
+			name:   "not exists",
+			dbType: Clickhouse,
+			build: func() *ConditionBuilder {
+				return NewConditionBuilder().AddCondition(&Condition{Operator: NotExists, Value: orders()})
+			},
+			// ClickHouse 的 Equal 在占位符后带一个空格
+			want: "SELECT * FROM `users` WHERE NOT EXISTS (SELECT user_id FROM orders WHERE status = ? )",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := conditionSQL(t, tt.dbType, tt.build())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != tt.want {
+				t.Errorf("query = %q, want %q", query, tt.want)
+			}
+			if last := args[len(args)-1]; last.Value != "paid" {
+				t.Errorf("args %v, want the subquery argument last", args)
+			}
+		})
+	}
+}
+
+func TestSubQueryDatabase(t *testing.T) {
+	db, _ := newFakeDB(t, Mysql)
+	sub := NewDatabase(Mysql).Use(db).Table("orders").Select("user_id").Query("status = ?", "paid")
+	query, _, err := conditionSQL(t, Mysql, NewConditionBuilder().AppendCondition("id", NotIn, sub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT * FROM `users` WHERE id not in (SELECT user_id FROM `orders` WHERE status = ?)"; query != want {
+		t.Errorf("query = %q, want %q", query, want)
+	}
+}
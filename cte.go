@@ -0,0 +1,126 @@
+package dac
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// cte 公用表表达式
+type cte struct {
+	name      string
+	columns   []string
+	query     interface{} // *gorm.DB 或 Union 各分支相连的 clause.Expr
+	recursive bool
+}
+
+// withClause WITH 子句，渲染在 SELECT 之前
+type withClause struct {
+	ctes []cte
+}
+
+func (w withClause) Name() string {
+	return "WITH"
+}
+
+func (w withClause) MergeClause(c *clause.Clause) {
+	if exist, ok := c.Expression.(withClause); ok {
+		w.ctes = append(append([]cte{}, exist.ctes...), w.ctes...)
+	}
+	c.Name = ""
+	c.Expression = w
+}
+
+func (w withClause) Build(builder clause.Builder) {
+	builder.WriteString("WITH ")
+	for _, c := range w.ctes {
+		if c.recursive {
+			builder.WriteString("RECURSIVE ")
+			break
+		}
+	}
+	for i, c := range w.ctes {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteQuoted(c.name)
+		if len(c.columns) > 0 {
+			builder.WriteByte('(')
+			for j, column := range c.columns {
+				if j > 0 {
+					builder.WriteByte(',')
+				}
+				builder.WriteQuoted(column)
+			}
+			builder.WriteByte(')')
+		}
+		builder.WriteString(" AS (")
+		builder.AddVar(builder, c.query)
+		builder.WriteByte(')')
+	}
+}
+
+// withScope 在查询子句前加入 WITH，gorm 默认的查询子句中没有 WITH
+func withScope(db *gorm.DB) *gorm.DB {
+	clauses := db.Statement.BuildClauses
+	if len(clauses) == 0 {
+		clauses = db.Callback().Query().Clauses
+	}
+	if len(clauses) > 0 && clauses[0] == "WITH" {
+		return db
+	}
+	db.Statement.BuildClauses = append([]string{"WITH"}, clauses...)
+	return db
+}
+
+// With 添加公用表表达式 WITH name AS (subquery)，之后可以通过 Table、Join 引用 name
+// MySQL 8.0 以下版本不支持，版本由 ServerVersion 设置或自动检测
+func (d *Database) With(name string, subquery *Database) *Database {
+	return d.with(cte{name: name}, subquery)
+}
+
+// WithRecursive 添加递归公用表表达式 WITH RECURSIVE name(columns) AS (subquery)
+// subquery 通常是基础查询与递归查询的 UnionAll，渲染为 anchor UNION ALL recursive，不再包装为子查询，
+// 此时 UnionAll 的结果上不能再添加条件、排序等子句
+func (d *Database) WithRecursive(name string, subquery *Database, columns ...string) *Database {
+	return d.with(cte{name: name, columns: columns, recursive: true}, subquery)
+}
+
+func (d *Database) with(c cte, subquery *Database) *Database {
+	tx := d.getInstance()
+	dialect := tx.dialect()
+	switch tx.DBType {
+	case Mysql:
+		if !dialect.AtLeast(8, 0) {
+			tx.err = fmt.Errorf("%w: WITH requires MySQL 8.0, server version is %s", ErrUnsupported, dialect.version())
+			return tx
+		}
+	case Clickhouse:
+		if c.recursive && !dialect.AtLeast(24, 4) {
+			tx.err = fmt.Errorf("%w: WITH RECURSIVE requires ClickHouse 24.4, server version is %s", ErrUnsupported, dialect.version())
+			return tx
+		}
+	case Postgres:
+	default:
+		tx.err = fmt.Errorf("%w: WITH on %s", ErrUnsupported, tx.DBType)
+		return tx
+	}
+	if subquery.err != nil {
+		tx.err = subquery.err
+		return tx
+	}
+	if err := subquery.prepare(opQuery, nil); err != nil {
+		tx.err = err
+		return tx
+	}
+	c.query = subquery.DB()
+	if c.recursive && subquery.unionBody != nil {
+		if stmt := subquery.db.Statement; len(stmt.Clauses) > 0 || len(stmt.Selects) > 0 || len(stmt.Joins) > 0 {
+			tx.err = fmt.Errorf("WITH RECURSIVE %s: the union must not have further clauses", c.name)
+			return tx
+		}
+		c.query = *subquery.unionBody
+	}
+	return tx.useSourceDB(tx.db.Clauses(withClause{ctes: []cte{c}}).Scopes(withScope))
+}
@@ -0,0 +1,132 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type categoryNode struct {
+	ID       uint
+	ParentID uint
+}
+
+func TestWith(t *testing.T) {
+	tests := []struct {
+		dbType DBType
+		want   string
+	}{
+		{Mysql, "WITH `paid` AS (SELECT user_id FROM `orders` WHERE status = ?) SELECT * FROM `paid` WHERE user_id > ?"},
+		{Postgres, `WITH "paid" AS (SELECT user_id FROM "orders" WHERE status = $1) SELECT * FROM "paid" WHERE user_id > $2`},
+		{Clickhouse, "WITH `paid` AS (SELECT user_id FROM `orders` WHERE status = ?) SELECT * FROM `paid` WHERE user_id > ?"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.dbType), func(t *testing.T) {
+			db, b := newFakeDB(t, tt.dbType)
+			var args []driver.NamedValue
+			b.query = func(query string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+				args = a
+				return nil, nil, nil
+			}
+			d := NewDatabase(tt.dbType).Use(db).ServerVersion("24.4")
+			paid := d.Table("orders").Select("user_id").Query("status = ?", "paid")
+			var out []map[string]interface{}
+			err := NewDatabase(tt.dbType).Use(db).ServerVersion("24.4").With("paid", paid).
+				Table("paid").Query("user_id > ?", 10).Find(&out).Error()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stmts := b.statements(); len(stmts) != 1 || stmts[0] != tt.want {
+				t.Errorf("statements %q, want %q", stmts, tt.want)
+			}
+			if len(args) != 2 || args[0].Value != "paid" || args[1].Value != int64(10) {
+				t.Errorf("args %v, want [paid 10]", args)
+			}
+		})
+	}
+}
+
+func TestWithRecursive(t *testing.T) {
+	tests := []struct {
+		dbType DBType
+		want   string
+	}{
+		{Mysql, "WITH RECURSIVE `tree`(`id`,`parent_id`) AS (SELECT id,parent_id FROM `categories` WHERE id = ? UNION ALL SELECT c.id,c.parent_id FROM categories AS c JOIN tree ON c.parent_id = tree.id) SELECT * FROM `tree`"},
+		{Postgres, `WITH RECURSIVE "tree"("id","parent_id") AS (SELECT id,parent_id FROM "categories" WHERE id = $1 UNION ALL SELECT c.id,c.parent_id FROM categories AS c JOIN tree ON c.parent_id = tree.id) SELECT * FROM "tree"`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.dbType), func(t *testing.T) {
+			db, b := newFakeDB(t, tt.dbType)
+			d := NewDatabase(tt.dbType).Use(db).ServerVersion("8.0.34")
+			anchor := d.Table("categories").Select("id", "parent_id").Query("id = ?", 1)
+			recursive := NewDatabase(tt.dbType).Use(db).Table("categories AS c").Select("c.id", "c.parent_id").
+				Joins("JOIN tree ON c.parent_id = tree.id")
+			var out []categoryNode
+			err := NewDatabase(tt.dbType).Use(db).ServerVersion("8.0.34").
+				WithRecursive("tree", anchor.UnionAll(recursive), "id", "parent_id").
+				Table("tree").Find(&out).Error()
+			if err != nil {
+				t.Fatal(err)
+			}
+			stmts := b.statements()
+			if len(stmts) != 1 || stmts[0] != tt.want {
+				t.Errorf("statements %q, want %q", stmts, tt.want)
+			}
+			if strings.Contains(stmts[0], "dac_union") {
+				t.Errorf("recursive body is wrapped in a derived table: %s", stmts[0])
+			}
+		})
+	}
+}
+
+func TestWithErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		dbType  DBType
+		version string
+		run     func(d *Database, sub *Database) *Database
+		want    error
+		message string
+	}{
+		{
+			name:    "mysql 5.7",
+			dbType:  Mysql,
+			version: "5.7.44",
+			run:     func(d, sub *Database) *Database { return d.With("t", sub) },
+			want:    ErrUnsupported,
+		},
+		{
+			name:    "clickhouse recursive before 24.4",
+			dbType:  Clickhouse,
+			version: "23.8",
+			run:     func(d, sub *Database) *Database { return d.WithRecursive("t", sub) },
+			want:    ErrUnsupported,
+		},
+		{
+			name:    "recursive union with further clauses",
+			dbType:  Postgres,
+			version: "16.2",
+			run: func(d, sub *Database) *Database {
+				other := NewDatabase(Postgres).Use(d.DB()).Table("b").Select("id")
+				return d.WithRecursive("t", sub.UnionAll(other).Order("id"))
+			},
+			message: "must not have further clauses",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := newFakeDB(t, tt.dbType)
+			d := NewDatabase(tt.dbType).Use(db).ServerVersion(tt.version)
+			sub := NewDatabase(tt.dbType).Use(db).Table("a").Select("id")
+			var out []map[string]interface{}
+			err := tt.run(d, sub).Table("t").Find(&out).Error()
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if tt.message != "" && (err == nil || !strings.Contains(err.Error(), tt.message)) {
+				t.Errorf("err = %v, want %q", err, tt.message)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package dac
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -75,6 +76,8 @@ func QuoteAfterDot(s string) string {
 	return s[:dotIndex+1] + `"` + s[dotIndex+1:] + `"`
 }
 
+// GenerateIfElseSql 将条件转换为 true/false
+// Deprecated: 依赖全局 DB 的数据库类型，请使用 Case
 func GenerateIfElseSql(subQuery string) string {
 	switch DB.DBType {
 	case Postgres:
@@ -87,7 +90,12 @@ func GenerateIfElseSql(subQuery string) string {
 		)
 	case Mysql:
 		return fmt.Sprintf(
-			`SELECT IF(%s, true, false) `,
+			`IF(%s, true, false)`,
+			subQuery,
+		)
+	case Clickhouse:
+		return fmt.Sprintf(
+			`if(%s, true, false)`,
 			subQuery,
 		)
 	default:
@@ -95,6 +103,96 @@ func GenerateIfElseSql(subQuery string) string {
 	}
 }
 
+// CaseExpr CASE WHEN 表达式
+type CaseExpr struct {
+	whens     []caseWhen
+	elseValue interface{}
+	hasElse   bool
+}
+
+type caseWhen struct {
+	cond   *ConditionBuilder
+	result interface{}
+}
+
+// Case 创建 CASE WHEN 表达式，ClickHouse 渲染为 multiIf
+// 结果可以是 Expression、ColumnRef 或普通值，普通值作为绑定参数
+func Case() *CaseExpr {
+	return &CaseExpr{}
+}
+
+// When 添加分支，cond 满足时返回 result
+func (c *CaseExpr) When(cond *ConditionBuilder, result interface{}) *CaseExpr {
+	c.whens = append(c.whens, caseWhen{cond: cond, result: result})
+	return c
+}
+
+// Else 所有分支都不满足时返回 result，未设置时为 NULL
+func (c *CaseExpr) Else(result interface{}) *CaseExpr {
+	c.elseValue = result
+	c.hasElse = true
+	return c
+}
+
+// As 设置别名
+func (c *CaseExpr) As(alias string) Expression {
+	return As(c, alias)
+}
+
+func (c *CaseExpr) Build(dialect Dialect) (string, []interface{}, error) {
+	if len(c.whens) == 0 {
+		return "", nil, fmt.Errorf("CASE requires at least one WHEN branch")
+	}
+	var (
+		parts []string
+		args  []interface{}
+	)
+	for _, w := range c.whens {
+		cond, condArgs := w.cond.Build(dialect.DBType)
+		if err := w.cond.Error(); err != nil {
+			return "", nil, err
+		}
+		if cond == "" {
+			return "", nil, fmt.Errorf("CASE WHEN branch has an empty condition")
+		}
+		result, resultArgs, err := buildOperand(dialect, w.result)
+		if err != nil {
+			return "", nil, err
+		}
+		if dialect.DBType == Clickhouse {
+			parts = append(parts, "("+cond+")", result)
+		} else {
+			parts = append(parts, fmt.Sprintf("WHEN (%s) THEN %s", cond, result))
+		}
+		args = append(args, condArgs...)
+		args = append(args, resultArgs...)
+	}
+	elseValue := "NULL"
+	if c.hasElse {
+		result, resultArgs, err := buildOperand(dialect, c.elseValue)
+		if err != nil {
+			return "", nil, err
+		}
+		elseValue = result
+		args = append(args, resultArgs...)
+	}
+	if dialect.DBType == Clickhouse {
+		return fmt.Sprintf("multiIf(%s, %s)", strings.Join(parts, ", "), elseValue), args, nil
+	}
+	return fmt.Sprintf("CASE %s ELSE %s END", strings.Join(parts, " "), elseValue), args, nil
+}
+
+// buildOperand 渲染表达式中的操作数，Expression 和 ColumnRef 直接渲染，其余值作为绑定参数
+func buildOperand(dialect Dialect, value interface{}) (string, []interface{}, error) {
+	switch v := value.(type) {
+	case Expression:
+		return unalias(v).Build(dialect)
+	case ColumnRef:
+		return v.quoted(dialect.DBType), nil, nil
+	}
+	return "?", []interface{}{value}, nil
+}
+
 func Max(field string, alias ...string) string {
 	return buildAliasStr(fmt.Sprintf("max(%v)", field), alias...)
 }
@@ -146,3 +244,245 @@ func GroupConcat(field string, alias ...string) string {
 func Concat(field string, alias ...string) string {
 	return buildAliasStr(fmt.Sprintf("concat(%s)", field), alias...)
 }
+
+// TimeUnit 时间分桶的单位
+type TimeUnit string
+
+const (
+	Minute  TimeUnit = "minute"
+	Hour    TimeUnit = "hour"
+	Day     TimeUnit = "day"
+	Week    TimeUnit = "week" // 以周一作为一周的开始
+	Month   TimeUnit = "month"
+	Quarter TimeUnit = "quarter"
+	Year    TimeUnit = "year"
+)
+
+var timezoneRe = regexp.MustCompile(`^[A-Za-z0-9_+\-:/]+$`)
+
+// DateTruncExpr 时间分桶表达式
+type DateTruncExpr struct {
+	field string
+	unit  TimeUnit
+	tz    string
+}
+
+// DateTrunc 将时间截断到 unit 的开始，tz 为空时使用数据库会话时区
+// ClickHouse 渲染为 toStartOfHour 等函数，PostgreSQL 渲染为 date_trunc，MySQL 使用 DATE_FORMAT 等函数拼接
+func DateTrunc(field string, unit TimeUnit, tz ...string) *DateTruncExpr {
+	e := &DateTruncExpr{field: field, unit: unit}
+	if len(tz) > 0 {
+		e.tz = tz[0]
+	}
+	return e
+}
+
+// As 设置别名
+func (e *DateTruncExpr) As(alias string) Expression {
+	return As(e, alias)
+}
+
+func (e *DateTruncExpr) Build(dialect Dialect) (string, []interface{}, error) {
+	if e.tz != "" && !timezoneRe.MatchString(e.tz) {
+		return "", nil, fmt.Errorf("invalid timezone %q", e.tz)
+	}
+	switch dialect.DBType {
+	case Clickhouse:
+		return e.buildClickhouse()
+	case Postgres:
+		field := e.field
+		if e.tz != "" {
+			field = fmt.Sprintf("(%s AT TIME ZONE '%s')", field, e.tz)
+		}
+		switch e.unit {
+		case Minute, Hour, Day, Week, Month, Quarter, Year:
+			return fmt.Sprintf("date_trunc('%s', %s)", e.unit, field), nil, nil
+		}
+	case Mysql:
+		return e.buildMysql()
+	default:
+		return "", nil, fmt.Errorf("DateTrunc is not supported on %s", dialect.DBType)
+	}
+	return "", nil, fmt.Errorf("unsupported time unit %q", e.unit)
+}
+
+func (e *DateTruncExpr) buildClickhouse() (string, []interface{}, error) {
+	tz := ""
+	if e.tz != "" {
+		tz = fmt.Sprintf(", '%s'", e.tz)
+	}
+	switch e.unit {
+	case Minute, Hour, Day:
+		return fmt.Sprintf("toStartOfInterval(%s, INTERVAL 1 %s%s)", e.field, e.unit, tz), nil, nil
+	case Week:
+		return fmt.Sprintf("toStartOfWeek(%s, 1%s)", e.field, tz), nil, nil
+	case Month:
+		return fmt.Sprintf("toStartOfMonth(%s%s)", e.field, tz), nil, nil
+	case Quarter:
+		return fmt.Sprintf("toStartOfQuarter(%s%s)", e.field, tz), nil, nil
+	case Year:
+		return fmt.Sprintf("toStartOfYear(%s%s)", e.field, tz), nil, nil
+	}
+	return "", nil, fmt.Errorf("unsupported time unit %q", e.unit)
+}
+
+func (e *DateTruncExpr) buildMysql() (string, []interface{}, error) {
+	field := e.field
+	if e.tz != "" {
+		field = fmt.Sprintf("CONVERT_TZ(%s, @@session.time_zone, '%s')", field, e.tz)
+	}
+	switch e.unit {
+	case Minute:
+		return fmt.Sprintf("CAST(DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:%%i:00') AS DATETIME)", field), nil, nil
+	case Hour:
+		return fmt.Sprintf("CAST(DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:00:00') AS DATETIME)", field), nil, nil
+	case Day:
+		return fmt.Sprintf("CAST(DATE(%s) AS DATETIME)", field), nil, nil
+	case Week:
+		return fmt.Sprintf("CAST(DATE_SUB(DATE(%s), INTERVAL WEEKDAY(%s) DAY) AS DATETIME)", field, field), nil, nil
+	case Month:
+		return fmt.Sprintf("CAST(DATE_FORMAT(%s, '%%Y-%%m-01') AS DATETIME)", field), nil, nil
+	case Quarter:
+		return fmt.Sprintf("CAST(MAKEDATE(YEAR(%s), 1) + INTERVAL QUARTER(%s) - 1 QUARTER AS DATETIME)", field, field), nil, nil
+	case Year:
+		return fmt.Sprintf("CAST(MAKEDATE(YEAR(%s), 1) AS DATETIME)", field), nil, nil
+	}
+	return "", nil, fmt.Errorf("unsupported time unit %q", e.unit)
+}
+
+// 窗口帧边界
+const (
+	UnboundedPreceding = "UNBOUNDED PRECEDING"
+	UnboundedFollowing = "UNBOUNDED FOLLOWING"
+	CurrentRow         = "CURRENT ROW"
+)
+
+// Preceding 当前行之前的第 n 行
+func Preceding(n int) string {
+	return fmt.Sprintf("%d PRECEDING", n)
+}
+
+// Following 当前行之后的第 n 行
+func Following(n int) string {
+	return fmt.Sprintf("%d FOLLOWING", n)
+}
+
+// WindowExpr 窗口函数表达式
+type WindowExpr struct {
+	fn         string
+	args       []string
+	vars       []interface{}
+	offsetFunc bool // lag/lead，ClickHouse 中需要使用 lagInFrame/leadInFrame
+	partition  []string
+	order      []string
+	frame      string
+}
+
+// RowNumber ROW_NUMBER()
+func RowNumber() *WindowExpr {
+	return &WindowExpr{fn: "row_number"}
+}
+
+// Rank RANK()
+func Rank() *WindowExpr {
+	return &WindowExpr{fn: "rank"}
+}
+
+// DenseRank DENSE_RANK()
+func DenseRank() *WindowExpr {
+	return &WindowExpr{fn: "dense_rank"}
+}
+
+// Lag 当前行之前第 offset 行的值，defaultValue 为超出范围时的默认值
+func Lag(field string, offset int, defaultValue ...interface{}) *WindowExpr {
+	return newOffsetWindow("lag", field, offset, defaultValue...)
+}
+
+// Lead 当前行之后第 offset 行的值，defaultValue 为超出范围时的默认值
+func Lead(field string, offset int, defaultValue ...interface{}) *WindowExpr {
+	return newOffsetWindow("lead", field, offset, defaultValue...)
+}
+
+func newOffsetWindow(fn, field string, offset int, defaultValue ...interface{}) *WindowExpr {
+	w := &WindowExpr{fn: fn, args: []string{field, fmt.Sprintf("%d", offset)}, offsetFunc: true}
+	if len(defaultValue) > 0 {
+		w.args = append(w.args, "?")
+		w.vars = append(w.vars, defaultValue[0])
+	}
+	return w
+}
+
+// SumOver SUM() OVER，配合 OrderBy 和 RowsBetween 计算累计值
+func SumOver(field string) *WindowExpr {
+	return &WindowExpr{fn: "sum", args: []string{field}}
+}
+
+// AvgOver AVG() OVER
+func AvgOver(field string) *WindowExpr {
+	return &WindowExpr{fn: "avg", args: []string{field}}
+}
+
+// CountOver COUNT() OVER
+func CountOver(field string) *WindowExpr {
+	return &WindowExpr{fn: "count", args: []string{field}}
+}
+
+// PartitionBy 设置 PARTITION BY
+func (w *WindowExpr) PartitionBy(fields ...string) *WindowExpr {
+	w.partition = append(w.partition, fields...)
+	return w
+}
+
+// OrderBy 设置窗口内的 ORDER BY，字段可以带 DESC
+func (w *WindowExpr) OrderBy(fields ...string) *WindowExpr {
+	w.order = append(w.order, fields...)
+	return w
+}
+
+// RowsBetween 设置 ROWS BETWEEN start AND end
+func (w *WindowExpr) RowsBetween(start, end string) *WindowExpr {
+	w.frame = fmt.Sprintf("ROWS BETWEEN %s AND %s", start, end)
+	return w
+}
+
+// As 设置别名
+func (w *WindowExpr) As(alias string) Expression {
+	return As(w, alias)
+}
+
+func (w *WindowExpr) Build(dialect Dialect) (string, []interface{}, error) {
+	fn := w.fn
+	frame := w.frame
+	switch dialect.DBType {
+	case Mysql:
+		if !dialect.AtLeast(8, 0) {
+			return "", nil, fmt.Errorf("%w: window function %s requires MySQL 8.0, server version is %s", ErrUnsupported, w.fn, dialect.version())
+		}
+	case Postgres:
+	case Clickhouse:
+		if !dialect.AtLeast(21, 9) {
+			return "", nil, fmt.Errorf("%w: window function %s requires ClickHouse 21.9, server version is %s", ErrUnsupported, w.fn, dialect.version())
+		}
+		// ClickHouse 的 lagInFrame/leadInFrame 受窗口帧限制，默认使用整个分区才与标准 lag/lead 一致
+		if w.offsetFunc {
+			fn += "InFrame"
+			if frame == "" {
+				frame = fmt.Sprintf("ROWS BETWEEN %s AND %s", UnboundedPreceding, UnboundedFollowing)
+			}
+		}
+	default:
+		return "", nil, fmt.Errorf("%w: window functions on %s", ErrUnsupported, dialect.DBType)
+	}
+
+	var over []string
+	if len(w.partition) > 0 {
+		over = append(over, "PARTITION BY "+strings.Join(w.partition, ","))
+	}
+	if len(w.order) > 0 {
+		over = append(over, "ORDER BY "+strings.Join(w.order, ","))
+	}
+	if frame != "" {
+		over = append(over, frame)
+	}
+	return fmt.Sprintf("%s(%s) OVER (%s)", fn, strings.Join(w.args, ", "), strings.Join(over, " ")), w.vars, nil
+}
@@ -0,0 +1,115 @@
+package dac
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCaseExpr(t *testing.T) {
+	sized := func() *CaseExpr {
+		return Case().
+			When(NewConditionBuilder().AppendCondition("amount", GreaterThan, 100), "large").
+			When(NewConditionBuilder().AppendCondition("amount", LessThan, 0), Column("refund"))
+	}
+	tests := []struct {
+		name   string
+		dbType DBType
+		expr   *CaseExpr
+		want   string
+		args   []interface{}
+	}{
+		{
+			name:   "mysql",
+			dbType: Mysql,
+			expr:   sized().Else("small"),
+			want:   "CASE WHEN (amount > ?) THEN ? WHEN (amount < ?) THEN `refund` ELSE ? END",
+			args:   []interface{}{100, "large", 0, "small"},
+		},
+		{
+			name:   "postgres",
+			dbType: Postgres,
+			expr:   sized().Else("small"),
+			want:   `CASE WHEN (amount > ?) THEN ? WHEN (amount < ?) THEN "refund" ELSE ? END`,
+			args:   []interface{}{100, "large", 0, "small"},
+		},
+		{
+			name:   "clickhouse",
+			dbType: Clickhouse,
+			expr:   sized().Else("small"),
+			want:   "multiIf((amount > ?), ?, (amount < ?), `refund`, ?)",
+			args:   []interface{}{100, "large", 0, "small"},
+		},
+		{
+			name:   "mysql without else",
+			dbType: Mysql,
+			expr:   sized(),
+			want:   "CASE WHEN (amount > ?) THEN ? WHEN (amount < ?) THEN `refund` ELSE NULL END",
+			args:   []interface{}{100, "large", 0},
+		},
+		{
+			name:   "clickhouse without else",
+			dbType: Clickhouse,
+			expr:   sized(),
+			want:   "multiIf((amount > ?), ?, (amount < ?), `refund`, NULL)",
+			args:   []interface{}{100, "large", 0},
+		},
+		{
+			name:   "expression result",
+			dbType: Postgres,
+			expr:   Case().When(NewConditionBuilder().AppendCondition("kind", In, []string{"a"}), DateTrunc("created_at", Day)).Else(Column("updated_at")),
+			want:   `CASE WHEN (kind in (?)) THEN date_trunc('day', created_at) ELSE "updated_at" END`,
+			args:   []interface{}{[]string{"a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := tt.expr.Build(Dialect{DBType: tt.dbType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.want {
+				t.Errorf("sql = %q, want %q", sql, tt.want)
+			}
+			if fmt.Sprint(args) != fmt.Sprint(tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCaseExprErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr *CaseExpr
+		want string
+	}{
+		{"no branch", Case().Else(1), "at least one WHEN"},
+		{"empty condition", Case().When(NewConditionBuilder(), 1), "empty condition"},
+		{"condition error", Case().When(NewConditionBuilder().AppendCondition("age", Between, 1), 1), "between"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := tt.expr.Build(Dialect{DBType: Mysql}); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaseExprSelect(t *testing.T) {
+	db, b := newFakeDB(t, Postgres)
+	var out []map[string]interface{}
+	size := Case().
+		When(NewConditionBuilder().AppendCondition("amount", GreaterThan, 100), "large").
+		Else("small")
+	err := NewDatabase(Postgres).Use(db).Table("orders").SelectExpr("id", size.As("size")).
+		Query("status = ?", "paid").Find(&out).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT id,CASE WHEN (amount > $1) THEN $2 ELSE $3 END AS size FROM "orders" WHERE status = $4`
+	if stmts := b.statements(); len(stmts) != 1 || stmts[0] != want {
+		t.Errorf("statements %q, want %q", stmts, want)
+	}
+}
@@ -1,11 +1,14 @@
 package dac
 
 import (
+	"database/sql"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"reflect"
 	"runtime"
 	"strings"
+	"time"
 )
 
 // DataAccess 数据访问接口
@@ -48,6 +51,30 @@ type Database struct {
 	da     DataAccess
 	err    error
 	dbM    map[DBType]*gorm.DB
+
+	batchSize int    // 批量写入条数
+	version   string // 数据库服务端版本
+
+	selectAliases []selectAlias // Select 中设置了别名的表达式
+	selectColumns []string      // Select 的字段列表，用于 Union 检查列
+	derived       bool          // 查询的是 Union 等派生表，不再添加租户条件
+	unionBody     *clause.Expr  // Union 各分支直接相连的语句，用作 WithRecursive 的内容
+
+	tenant        interface{} // WithTenant 设置的租户
+	crossTenant   string      // CrossTenant 的原因
+	tenantApplied bool        // 已添加租户条件
+
+	deletedMode       deletedMode // 是否查询已软删除的行
+	softDeleteApplied bool        // 已添加软删除条件
+
+	cacheTTL  time.Duration        // 查询结果缓存时间
+	cacheTags []string             // 缓存标签
+	pending   *pendingInvalidation // Transaction 中写入的表，提交后再使缓存失效
+
+	metrics MetricsRecorder // 指标接收者
+
+	retry       *RetryPolicy // 重试策略，为 nil 时使用 DefaultRetryPolicy
+	retryWrites bool         // 写语句可以重试
 }
 
 var DB *Database
@@ -72,6 +99,9 @@ func (d *Database) GetDB() *gorm.DB {
 // Use 传入 db
 func (d *Database) Use(db *gorm.DB) *Database {
 	tx := d.getInstance()
+	if db != nil {
+		registerCapture(db)
+	}
 	tx.db = db
 	return tx
 }
@@ -100,7 +130,15 @@ func (d *Database) AutoMigrate(dst ...interface{}) error {
 			return err
 		}
 	}
-	return tx.db.AutoMigrate(dst...)
+	if err := tx.db.AutoMigrate(dst...); err != nil {
+		return err
+	}
+	for _, v := range dst {
+		if err := tx.createFullTextIndexes(v); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // autoMigrateStruct 递归解析结构体
@@ -122,6 +160,12 @@ func autoMigrateStruct(dbType DBType, t reflect.Type) error {
 		}
 
 		typeValue := extractTypeFromGormTag(tag)
+		if typeValue != "" && field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() != reflect.Uint8 {
+			if !IsArrayTypeSupported(dbType, typeValue) {
+				return fmt.Errorf("Field %s of struct %s has unsupported array type %s for %s\n", field.Name, t.Name(), typeValue, dbType)
+			}
+			continue
+		}
 		if typeValue != "" {
 			if !IsDatabaseTypeSupported(typeValue) {
 				return fmt.Errorf("Field %s of struct %s has unsupported tag type %s\n", field.Name, t.Name(), typeValue)
@@ -170,10 +214,123 @@ func (d *Database) Raw(sql string, values ...interface{}) *Database {
 	return tx.useSourceDB(tx.db.Raw(sql, values...))
 }
 
+// Exec 执行原始 SQL
+func (d *Database) Exec(sql string, values ...interface{}) *Database {
+	tx := d.getInstance()
+	defer tx.observe("exec", nil)()
+	return tx.useSourceDB(tx.db.Exec(sql, values...))
+}
+
+// Transaction 在事务中执行 fn，fn 返回错误或 panic 时回滚，嵌套调用时使用 SavePoint
+// 返回死锁等可重试的错误时按重试策略重新执行整个事务，fn 可能被调用多次，不应有事务之外的副作用
+// 事务中写入的表在最外层事务提交后才使缓存失效，回滚时不失效
+func (d *Database) Transaction(fn func(tx *Database) error) error {
+	tx := d.getInstance()
+	if tx.err != nil {
+		return tx.err
+	}
+	run := func() error {
+		pending := tx.pending
+		if pending == nil {
+			pending = &pendingInvalidation{}
+		}
+		err := tx.db.Transaction(func(gtx *gorm.DB) error {
+			t := tx.clone(gtx)
+			t.pending = pending
+			return fn(t)
+		})
+		if err == nil && tx.pending == nil {
+			pending.flush()
+		}
+		return err
+	}
+	policy, ok := tx.retryPolicy()
+	if !ok {
+		return run()
+	}
+	return policy.do(tx.db.Statement.Context, tx.DBType, run)
+}
+
 // Find 查询
 func (d *Database) Find(out interface{}) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.Find(out))
+	defer tx.observe("find", out)()
+	if tx.prepare(opQuery, out) != nil {
+		return tx
+	}
+	return tx.query(out, func(db *gorm.DB) *gorm.DB {
+		return db.Find(out)
+	})
+}
+
+// FindInBatches 分批查询，每批查询后调用 fn
+// 翻页使用主键游标（WHERE pk > 上一批最后的主键 ORDER BY pk）而不是 OFFSET，会保留 Where 设置的条件
+func (d *Database) FindInBatches(dest interface{}, batchSize int, fn func(tx *Database, batch int) error) *Database {
+	tx := d.getInstance()
+	defer tx.observe("find_in_batches", dest)()
+	if tx.prepare(opQuery, dest) != nil {
+		return tx
+	}
+	return tx.useSourceDB(tx.db.FindInBatches(dest, batchSize, func(btx *gorm.DB, batch int) error {
+		return fn(tx.clone(btx), batch)
+	}))
+}
+
+// Rows 执行查询并返回游标，调用方负责关闭
+func (d *Database) Rows() (rows *sql.Rows, err error) {
+	tx := d.getInstance()
+	if recorder, slow := tx.metricsRecorder(), getSlowQueryConfig(tx.DBType); recorder != nil || slow != nil {
+		start := time.Now()
+		defer func() {
+			tx.finish(recorder, slow, "rows", nil, time.Since(start), tx.db, err)
+		}()
+	}
+	if tx.err != nil {
+		return nil, tx.err
+	}
+	if err := tx.prepare(opQuery, nil); err != nil {
+		return nil, err
+	}
+	policy, ok := tx.retryPolicy()
+	if !ok {
+		return tx.db.Rows()
+	}
+	base := tx.db.Session(&gorm.Session{})
+	err = policy.do(tx.db.Statement.Context, tx.DBType, func() error {
+		rows, err = base.Rows()
+		return err
+	})
+	return rows, err
+}
+
+// ScanRows 将游标当前行扫描到 dest
+func (d *Database) ScanRows(rows *sql.Rows, dest interface{}) error {
+	tx := d.getInstance()
+	return tx.db.ScanRows(rows, dest)
+}
+
+// Each 逐行流式读取查询结果，不会一次性加载到内存，fn 返回错误时停止
+// 未设置 Table 或 Model 时使用 T 作为模型
+func Each[T any](d *Database, fn func(row T) error) error {
+	tx := d.getInstance()
+	if tx.db.Statement.Model == nil && tx.db.Statement.Table == "" && tx.db.Statement.TableExpr == nil {
+		tx.useSourceDB(tx.db.Model(new(T)))
+	}
+	rows, err := tx.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row T
+		if err := tx.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 // Unscoped 软链接
@@ -182,35 +339,116 @@ func (d *Database) Unscoped() *Database {
 	return tx.useSourceDB(tx.db.Unscoped())
 }
 
-// Create 创建
+// Create 创建，context 中有操作人时填充 created_by、updated_by，见 AuditTableInfo
 func (d *Database) Create(out interface{}) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.Create(out))
+	defer tx.observe("create", out)()
+	if tx.prepare(opCreate, out) != nil {
+		return tx
+	}
+	return tx.write(opCreate, out, func(tx *Database) {
+		if _, err := tx.lockVersion(opCreate, out); err != nil {
+			tx.err = err
+			return
+		}
+		tx.useSourceDB(tx.db.Create(out))
+	})
 }
+
+// Save 保存所有字段，主键为零值时创建
 func (d *Database) Save(out interface{}) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.Save(out))
+	defer tx.observe("save", out)()
+	op := opUpdate
+	if s, _ := tx.target(out); s != nil && len(primaryKeyConditions(tx.db.Statement.Context, s, out)) == 0 {
+		op = opCreate
+	}
+	if tx.prepare(op, out) != nil {
+		return tx
+	}
+	return tx.write(op, out, func(tx *Database) {
+		lock, err := tx.lockVersion(op, out)
+		if err != nil {
+			tx.err = err
+			return
+		}
+		if lock == nil && !tx.filtered() {
+			tx.useSourceDB(tx.db.Save(out))
+			return
+		}
+		// 指定 Select 避免 gorm 在没有更新到行时改为 upsert，覆盖其他人的修改、其他租户的行或恢复已删除的行
+		if len(tx.db.Statement.Selects) == 0 {
+			tx.db = tx.db.Select("*")
+		}
+		tx.useSourceDB(tx.db.Save(out))
+		if lock != nil {
+			tx.err = lock.check(tx.db)
+		}
+	})
 }
 
 // Updates  根据 `struct` 更新属性，只会更新非零值的字段
 func (d *Database) Updates(out interface{}) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.Updates(out))
+	defer tx.observe("updates", out)()
+	if tx.prepare(opUpdate, out) != nil {
+		return tx
+	}
+	return tx.write(opUpdate, out, func(tx *Database) {
+		lock, err := tx.lockVersion(opUpdate, out)
+		if err != nil {
+			tx.err = err
+			return
+		}
+		if lock == nil {
+			tx.useSourceDB(tx.db.Updates(out))
+			return
+		}
+		if selects := tx.db.Statement.Selects; len(selects) > 0 && !versionColumnSelected(selects, lock.field.DBName) {
+			tx.db = tx.db.Select(append(selects, lock.field.DBName))
+		}
+		tx.useSourceDB(tx.db.Updates(out))
+		tx.err = lock.check(tx.db)
+	})
 }
 
-// Update 更新单个列
+// Update 更新单个列，context 中有操作人时同时更新 updated_by
+// 表记录变更时会先读取所有匹配条件的行作为变更前的值，超过 AuditMaxRows 时返回 ErrAuditTooManyRows
 func (d *Database) Update(column string, value interface{}) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.Update(column, value))
+	defer tx.observe("update", nil)()
+	if tx.prepare(opUpdate, nil) != nil {
+		return tx
+	}
+	if by, actor, ok := tx.updatedByColumn(); ok && by != column {
+		return tx.write(opUpdate, nil, func(tx *Database) {
+			tx.useSourceDB(tx.db.Updates(map[string]interface{}{column: value, by: actor}))
+		})
+	}
+	return tx.write(opUpdate, nil, func(tx *Database) {
+		tx.useSourceDB(tx.db.Update(column, value))
+	})
 }
 
-// Delete  删除
+// Delete  删除，表设置了软删除策略时按策略软删除，见 SoftDeleteTableInfo
 func (d *Database) Delete(out interface{}) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.Delete(out))
+	defer tx.observe("delete", out)()
+	if tx.prepare(opDelete, out) != nil {
+		return tx
+	}
+	return tx.write(opDelete, out, func(tx *Database) {
+		if handled, err := tx.softDelete(out); handled {
+			if err != nil {
+				tx.err = err
+			}
+			return
+		}
+		tx.useSourceDB(tx.db.Delete(out))
+	})
 }
 
-// HardDelete 硬删除
+// HardDelete 硬删除，ClickHouse 上为 ALTER TABLE ... DELETE 的 mutation
 func (d *Database) HardDelete(out interface{}) *Database {
 	tx := d.getInstance()
 	return tx.Unscoped().Delete(out)
@@ -229,32 +467,55 @@ func (d *Database) Having(builder *ConditionBuilder) *Database {
 // Scan 将数据输出到指定的结构体
 func (d *Database) Scan(out interface{}) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.Scan(out))
+	defer tx.observe("scan", out)()
+	if tx.prepare(opQuery, out) != nil {
+		return tx
+	}
+	return tx.query(out, func(db *gorm.DB) *gorm.DB {
+		return db.Scan(out)
+	})
 }
 
 // First 查询第一条
 func (d *Database) First(out interface{}) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.First(out))
+	defer tx.observe("first", out)()
+	if tx.prepare(opQuery, out) != nil {
+		return tx
+	}
+	return tx.query(out, func(db *gorm.DB) *gorm.DB {
+		return db.First(out)
+	})
 }
 
 // Last 查询最后一条
 func (d *Database) Last(out interface{}) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.Last(out))
+	defer tx.observe("last", out)()
+	if tx.prepare(opQuery, out) != nil {
+		return tx
+	}
+	return tx.query(out, func(db *gorm.DB) *gorm.DB {
+		return db.Last(out)
+	})
 }
 
 // Count 查询数量
 func (d *Database) Count(count *int64) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.Count(count))
+	defer tx.observe("count", nil)()
+	if tx.prepare(opQuery, nil) != nil {
+		return tx
+	}
+	return tx.query(count, func(db *gorm.DB) *gorm.DB {
+		return db.Count(count)
+	})
 }
 
 // Joins 连接查询
 func (d *Database) Joins(query string, args ...interface{}) *Database {
 	tx := d.getInstance()
-	tx.db.Joins(query, args...)
-	return tx
+	return tx.useSourceDB(tx.db.Joins(query, args...))
 }
 func (d *Database) Join(tableWithAlias, condition string) *Database {
 	tx := d.getInstance()
@@ -274,24 +535,35 @@ func (d *Database) Preload(query string, args ...interface{}) *Database {
 
 // Select 查询字段
 func (d *Database) Select(fields ...string) *Database {
-	tx := d.getInstance()
-	var query string
+	exprs := make([]interface{}, 0, len(fields))
 	for _, v := range fields {
-		//field := parseField(v, d.DBType)
-		//生成查询 sql
-		if query == "" {
-			query = v
-		} else {
-			query += "," + v
-		}
+		exprs = append(exprs, v)
 	}
-	return tx.useSourceDB(tx.db.Select(query))
+	return d.SelectExpr(exprs...)
+}
+
+// SelectExpr 查询字段，字段可以是字符串或 Expression
+func (d *Database) SelectExpr(fields ...interface{}) *Database {
+	tx := d.getInstance()
+	query, args, err := tx.buildFields(fields)
+	if err != nil {
+		tx.err = err
+		return tx
+	}
+	tx.selectColumns = splitTopLevel(query)
+	return tx.useSourceDB(tx.db.Select(query, args...))
 }
 
 // Pluck 查询字段
 func (d *Database) Pluck(column string, desc any) *Database {
 	tx := d.getInstance()
-	return tx.useSourceDB(tx.db.Pluck(column, desc))
+	defer tx.observe("pluck", nil)()
+	if tx.prepare(opQuery, nil) != nil {
+		return tx
+	}
+	return tx.query(desc, func(db *gorm.DB) *gorm.DB {
+		return db.Pluck(column, desc)
+	})
 }
 
 // Model 设置模型
@@ -315,9 +587,18 @@ func (d *Database) Limit(page, pageSize int) *Database {
 // Group 分组
 func (d *Database) Group(group string) *Database {
 	tx := d.getInstance()
-	tx.db.Group(group)
-	//return d.useSourceDB(d.db.Group(group))
-	return tx
+	return tx.useSourceDB(tx.db.Group(group))
+}
+
+// GroupExpr 按表达式分组，表达式已在 Select 中设置别名时使用别名
+func (d *Database) GroupExpr(group Expression) *Database {
+	tx := d.getInstance()
+	column, _, err := tx.groupOrderExpression(group)
+	if err != nil {
+		tx.err = err
+		return tx
+	}
+	return tx.useSourceDB(tx.db.Clauses(clause.GroupBy{Columns: []clause.Column{column}}))
 }
 
 // Order 排序
@@ -326,6 +607,17 @@ func (d *Database) Order(order string) *Database {
 	return tx.useSourceDB(tx.db.Order(order))
 }
 
+// OrderExpr 按表达式排序，降序使用 Desc 包装
+func (d *Database) OrderExpr(order Expression) *Database {
+	tx := d.getInstance()
+	column, desc, err := tx.groupOrderExpression(order)
+	if err != nil {
+		tx.err = err
+		return tx
+	}
+	return tx.useSourceDB(tx.db.Order(clause.OrderByColumn{Column: column, Desc: desc}))
+}
+
 // Error 获取错误
 func (d *Database) Error() error {
 	tx := d.getInstance()
@@ -0,0 +1,139 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type batchRow struct {
+	ID        uint
+	TenantID  int64
+	Name      string
+	DeletedAt *time.Time
+}
+
+func (batchRow) TableName() string  { return "batch_rows" }
+func (batchRow) TableAlias() string { return "" }
+func (batchRow) SoftDelete(dbType DBType) SoftDelete {
+	return SoftDelete{Strategy: SoftDeleteTimestamp}
+}
+
+// batchRowsBackend 每次查询依次返回 pages 中的一页
+func batchRowsBackend(b *fakeBackend, pages ...[]uint) *[][]driver.NamedValue {
+	var args [][]driver.NamedValue
+	b.query = func(query string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		args = append(args, a)
+		if len(pages) == 0 {
+			return nil, nil, nil
+		}
+		var rows [][]driver.Value
+		for _, id := range pages[0] {
+			rows = append(rows, []driver.Value{int64(id), int64(7), "a", nil})
+		}
+		pages = pages[1:]
+		return []string{"id", "tenant_id", "name", "deleted_at"}, rows, nil
+	}
+	return &args
+}
+
+func TestFindInBatches(t *testing.T) {
+	db, b := newFakeDB(t, Mysql)
+	args := batchRowsBackend(b, []uint{1, 2}, []uint{3})
+	var rows []batchRow
+	var seen []uint
+	err := NewDatabase(Mysql).Use(db).WithTenant(int64(7)).Query("name = ?", "a").
+		FindInBatches(&rows, 2, func(tx *Database, batch int) error {
+			for _, r := range rows {
+				seen = append(seen, r.ID)
+			}
+			return nil
+		}).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 3 || seen[2] != 3 {
+		t.Errorf("seen %v, want [1 2 3]", seen)
+	}
+	want := []string{
+		"SELECT * FROM `batch_rows` WHERE name = ? AND `batch_rows`.`tenant_id` = ? AND `batch_rows`.`deleted_at` IS NULL ORDER BY `batch_rows`.`id` LIMIT 2",
+		"SELECT * FROM `batch_rows` WHERE name = ? AND `batch_rows`.`tenant_id` = ? AND `batch_rows`.`deleted_at` IS NULL AND `batch_rows`.`id` > ? ORDER BY `batch_rows`.`id` LIMIT 2",
+	}
+	stmts := b.statements()
+	if strings.Join(stmts, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("statements\n%s\nwant\n%s", strings.Join(stmts, "\n"), strings.Join(want, "\n"))
+	}
+	if a := (*args)[1]; len(a) != 3 || a[2].Value != int64(2) {
+		t.Errorf("second batch args %v, want cursor 2", a)
+	}
+}
+
+func TestFindInBatchesStopsOnError(t *testing.T) {
+	db, b := newFakeDB(t, Mysql)
+	batchRowsBackend(b, []uint{1, 2}, []uint{3, 4}, []uint{5})
+	stop := errors.New("stop")
+	var rows []batchRow
+	calls := 0
+	err := NewDatabase(Mysql).Use(db).FindInBatches(&rows, 2, func(tx *Database, batch int) error {
+		calls++
+		return stop
+	}).Error()
+	if !errors.Is(err, stop) {
+		t.Errorf("err = %v, want %v", err, stop)
+	}
+	if calls != 1 || b.count("SELECT") != 1 {
+		t.Errorf("calls = %d, selects = %d, want 1 and 1", calls, b.count("SELECT"))
+	}
+}
+
+func TestRowsScanRows(t *testing.T) {
+	db, b := newFakeDB(t, Postgres)
+	batchRowsBackend(b, []uint{1, 2})
+	d := NewDatabase(Postgres).Use(db).WithTenant(int64(7)).Model(&batchRow{})
+	rows, err := d.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []uint
+	for rows.Next() {
+		var r batchRow
+		if err := d.ScanRows(rows, &r); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, r.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("ids %v, want [1 2]", ids)
+	}
+	want := `SELECT * FROM "batch_rows" WHERE "batch_rows"."tenant_id" = $1 AND "batch_rows"."deleted_at" IS NULL`
+	if stmts := b.statements(); len(stmts) != 1 || stmts[0] != want {
+		t.Errorf("statements %q, want %q", stmts, want)
+	}
+}
+
+func TestEach(t *testing.T) {
+	db, b := newFakeDB(t, Mysql)
+	batchRowsBackend(b, []uint{1, 2, 3})
+	stop := errors.New("stop")
+	var ids []uint
+	err := Each(NewDatabase(Mysql).Use(db).WithTenant(int64(7)), func(r batchRow) error {
+		ids = append(ids, r.ID)
+		if r.ID == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("err = %v, want %v", err, stop)
+	}
+	if len(ids) != 2 {
+		t.Errorf("ids %v, want [1 2]", ids)
+	}
+	want := "SELECT * FROM `batch_rows` WHERE `batch_rows`.`tenant_id` = ? AND `batch_rows`.`deleted_at` IS NULL"
+	if stmts := b.statements(); len(stmts) != 1 || stmts[0] != want {
+		t.Errorf("statements %q, want %q", stmts, want)
+	}
+}
@@ -72,6 +72,25 @@ func IsDatabaseTypeSupported(fieldType string) bool {
 	return false
 }
 
+var (
+	clickhouseArrayRe = regexp.MustCompile(`^(?i)Array\(.+\)$`)
+	postgresArrayRe   = regexp.MustCompile(`^(?i)[a-z][a-z0-9 ]*(\(\d+(,\s*\d+)?\))?\[\]$`)
+)
+
+// IsArrayTypeSupported 检查切片字段的类型是否为数据库的数组类型
+// ClickHouse 为 Array(T)，Postgres 为 T[]，MySQL 使用 json 保存数组
+func IsArrayTypeSupported(dbType DBType, fieldType string) bool {
+	switch dbType {
+	case Clickhouse:
+		return clickhouseArrayRe.MatchString(fieldType)
+	case Postgres:
+		return postgresArrayRe.MatchString(fieldType)
+	case Mysql:
+		return strings.EqualFold(fieldType, "json")
+	}
+	return false
+}
+
 const (
 	GO_TYPE_UINT          = "uint"          // 无符号整数类型
 	GO_TYPE_UINT8         = "uint8"         // 无符号 8 位整数类型
@@ -0,0 +1,215 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// fakeBackend 测试用的数据库，记录执行的语句，由 exec、query 决定返回的结果
+type fakeBackend struct {
+	mu    sync.Mutex
+	log   []string
+	exec  func(query string, args []driver.NamedValue) (int64, error)
+	query func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error)
+}
+
+func (b *fakeBackend) record(query string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.log = append(b.log, query)
+}
+
+// statements 已执行的语句
+func (b *fakeBackend) statements() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.log...)
+}
+
+func (b *fakeBackend) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.log = nil
+}
+
+// count 包含 substr 的语句条数
+func (b *fakeBackend) count(substr string) int {
+	n := 0
+	for _, s := range b.statements() {
+		if strings.Contains(s, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	fakeBackendsMu sync.Mutex
+	fakeBackends   = map[string]*fakeBackend{}
+)
+
+func init() {
+	sql.Register("dac_fake", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeBackendsMu.Lock()
+	defer fakeBackendsMu.Unlock()
+	return &fakeConn{b: fakeBackends[name]}, nil
+}
+
+type fakeConn struct{ b *fakeBackend }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepare is not supported")
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.b.record("BEGIN")
+	return fakeTx{c.b}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.b.record(query)
+	var affected int64 = 1
+	if c.b.exec != nil {
+		n, err := c.b.exec(query, args)
+		if err != nil {
+			return nil, err
+		}
+		affected = n
+	}
+	return fakeResult(affected), nil
+}
+
+// fakeResult 影响的行数，自增 ID 固定为 0
+type fakeResult int64
+
+func (r fakeResult) LastInsertId() (int64, error) { return 0, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.b.record(query)
+	if c.b.query == nil {
+		return &fakeRows{}, nil
+	}
+	columns, rows, err := c.b.query(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+type fakeTx struct{ b *fakeBackend }
+
+func (t fakeTx) Commit() error   { t.b.record("COMMIT"); return nil }
+func (t fakeTx) Rollback() error { t.b.record("ROLLBACK"); return nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// fakeDialector 按数据库类型设置引号和占位符，语句交给 fakeBackend 执行
+type fakeDialector struct {
+	dbType DBType
+	dsn    string
+}
+
+func (d fakeDialector) Name() string { return string(d.dbType) }
+
+func (d fakeDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	conn, err := sql.Open("dac_fake", d.dsn)
+	if err != nil {
+		return err
+	}
+	db.ConnPool = conn
+	return nil
+}
+
+func (d fakeDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return fakeMigrator{migrator.Migrator{Config: migrator.Config{DB: db, Dialector: d}}}
+}
+
+type fakeMigrator struct{ migrator.Migrator }
+
+func (m fakeMigrator) HasTable(value interface{}) bool {
+	var count int64
+	m.DB.Raw("SELECT count(*) FROM information_schema.tables WHERE table_name = ?", value).Row().Scan(&count)
+	return count > 0
+}
+
+func (d fakeDialector) DataTypeOf(*schema.Field) string { return "" }
+func (d fakeDialector) DefaultValueOf(*schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+func (d fakeDialector) BindVarTo(w clause.Writer, stmt *gorm.Statement, v interface{}) {
+	if d.dbType == Postgres {
+		w.WriteString("$" + strconv.Itoa(len(stmt.Vars)))
+		return
+	}
+	w.WriteByte('?')
+}
+func (d fakeDialector) QuoteTo(w clause.Writer, s string) {
+	q := "`"
+	if d.dbType == Postgres {
+		q = `"`
+	}
+	w.WriteString(q + strings.ReplaceAll(s, ".", q+"."+q) + q)
+}
+func (d fakeDialector) Explain(sql string, vars ...interface{}) string {
+	return logger.ExplainSQL(sql, nil, `'`, vars...)
+}
+
+// newFakeDB 创建使用 fakeBackend 的 Database
+func newFakeDB(t *testing.T, dbType DBType) (*gorm.DB, *fakeBackend) {
+	t.Helper()
+	b := &fakeBackend{}
+	dsn := t.Name() + "/" + string(dbType)
+	fakeBackendsMu.Lock()
+	fakeBackends[dsn] = b
+	fakeBackendsMu.Unlock()
+	t.Cleanup(func() {
+		fakeBackendsMu.Lock()
+		delete(fakeBackends, dsn)
+		fakeBackendsMu.Unlock()
+	})
+	db, err := gorm.Open(fakeDialector{dbType: dbType, dsn: dsn}, &gorm.Config{
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.reset()
+	return db, b
+}
@@ -0,0 +1,207 @@
+package dac
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnsupported 数据库或其版本不支持该功能
+var ErrUnsupported = errors.New("unsupported by database")
+
+// Dialect 表达式渲染时的目标数据库
+type Dialect struct {
+	DBType  DBType
+	Version string // 数据库服务端版本，为空时视为最新版本
+
+	detect func() string // Version 为空时检测服务端版本，只在 AtLeast 中调用
+}
+
+var versionRe = regexp.MustCompile(`(\d+)(?:\.(\d+))?`)
+
+// version 返回 Version，为空时检测服务端版本
+func (d Dialect) version() string {
+	if d.Version == "" && d.detect != nil {
+		return d.detect()
+	}
+	return d.Version
+}
+
+// AtLeast 判断数据库版本是否不低于 major.minor，未设置版本时返回 true
+// 只有需要判断版本的表达式才会触发版本检测
+func (d Dialect) AtLeast(major, minor int) bool {
+	match := versionRe.FindStringSubmatch(d.version())
+	if match == nil {
+		return true
+	}
+	ma, _ := strconv.Atoi(match[1])
+	mi, _ := strconv.Atoi(match[2])
+	return ma > major || (ma == major && mi >= minor)
+}
+
+// Expression 按数据库类型渲染的 SQL 表达式，可用于 SelectExpr、GroupExpr、OrderExpr
+type Expression interface {
+	Build(dialect Dialect) (string, []interface{}, error)
+}
+
+// aliasExpr 带别名的表达式，别名只在 SelectExpr 中生效
+type aliasExpr struct {
+	Expression
+	alias string
+}
+
+// As 为表达式设置别名
+func As(expr Expression, alias string) Expression {
+	if a, ok := expr.(aliasExpr); ok {
+		expr = a.Expression
+	}
+	return aliasExpr{Expression: expr, alias: alias}
+}
+
+// descExpr 降序排序的表达式
+type descExpr struct {
+	Expression
+}
+
+// Desc 表达式按降序排序，用于 OrderExpr
+func Desc(expr Expression) Expression {
+	return descExpr{Expression: unalias(expr)}
+}
+
+func (e descExpr) Build(dialect Dialect) (string, []interface{}, error) {
+	sql, args, err := e.Expression.Build(dialect)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql + " DESC", args, nil
+}
+
+// unalias 去掉表达式的别名，GroupExpr 和 OrderExpr 中使用原始表达式
+func unalias(expr Expression) Expression {
+	if a, ok := expr.(aliasExpr); ok {
+		return a.Expression
+	}
+	return expr
+}
+
+func (d *Database) dialect() Dialect {
+	return Dialect{DBType: d.DBType, Version: d.version, detect: d.serverVersion}
+}
+
+// serverVersions 检测到的数据库服务端版本，以 gorm.Open 创建的连接池区分连接
+// Session、WithContext 会复制 *gorm.Config，但连接池不变，事务中也是同一个连接池
+var serverVersions sync.Map
+
+// serverVersion 返回 ServerVersion 设置的版本，未设置时执行 SELECT version() 检测，每个连接只检测一次
+// 检测失败时版本为空，视为最新版本，不支持的功能由数据库在执行时报错
+func (d *Database) serverVersion() string {
+	if d.version != "" || d.db == nil || d.db.DryRun {
+		return d.version
+	}
+	pool := d.db.Config.ConnPool
+	if v, ok := serverVersions.Load(pool); ok {
+		d.version = v.(string)
+		return d.version
+	}
+	var version string
+	if err := d.db.Session(&gorm.Session{NewDB: true}).Raw("SELECT version()").Row().Scan(&version); err != nil {
+		// 事务中失败可能是事务已中止，不缓存结果，之后在事务外重新检测
+		if d.inTransaction() {
+			return ""
+		}
+		version = ""
+	}
+	serverVersions.Store(pool, version)
+	d.version = version
+	return version
+}
+
+// ServerVersion 设置数据库服务端版本，用于判断窗口函数、CTE 等功能是否可用，如 "5.7.44"、"8.0.34"
+// 未设置时首次使用这些功能前会执行 SELECT version() 自动检测
+func (d *Database) ServerVersion(version string) *Database {
+	tx := d.getInstance()
+	tx.version = version
+	return tx
+}
+
+// buildFields 拼接 SelectExpr 的字段，字段可以是字符串或 Expression
+func (d *Database) buildFields(fields []interface{}) (string, []interface{}, error) {
+	var (
+		query []string
+		args  []interface{}
+	)
+	for _, v := range fields {
+		switch f := v.(type) {
+		case string:
+			//field := parseField(v, d.DBType)
+			query = append(query, f)
+		case aliasExpr:
+			sql, vars, err := f.Expression.Build(d.dialect())
+			if err != nil {
+				return "", nil, err
+			}
+			query = append(query, buildAliasStr(sql, f.alias))
+			d.selectAliases = append(d.selectAliases, selectAlias{expr: f.Expression, alias: f.alias})
+			args = append(args, vars...)
+		case Expression:
+			sql, vars, err := f.Build(d.dialect())
+			if err != nil {
+				return "", nil, err
+			}
+			query = append(query, sql)
+			args = append(args, vars...)
+		default:
+			return "", nil, fmt.Errorf("unsupported select field type %T", v)
+		}
+	}
+	return strings.Join(query, ","), args, nil
+}
+
+// selectAlias SelectExpr 中设置了别名的表达式
+type selectAlias struct {
+	expr  Expression
+	alias string
+}
+
+// selectedAlias 查找表达式在 SelectExpr 中的别名
+func (d *Database) selectedAlias(expr Expression) (string, bool) {
+	if !reflect.TypeOf(expr).Comparable() {
+		return "", false
+	}
+	for _, s := range d.selectAliases {
+		if reflect.TypeOf(s.expr) == reflect.TypeOf(expr) && s.expr == expr {
+			return s.alias, true
+		}
+	}
+	return "", false
+}
+
+// groupOrderExpression 渲染 GroupExpr、OrderExpr 中的表达式
+// 表达式已在 SelectExpr 中设置别名时返回别名，保证与 SelectExpr 中绑定参数的表达式一致；
+// 否则将参数内联为字面量，因为 gorm 的 GROUP BY、ORDER BY 列不支持绑定参数
+func (d *Database) groupOrderExpression(expr Expression) (column clause.Column, desc bool, err error) {
+	expr = unalias(expr)
+	if de, ok := expr.(descExpr); ok {
+		expr = de.Expression
+		desc = true
+	}
+	if name, ok := d.selectedAlias(expr); ok {
+		return clause.Column{Name: name}, desc, nil
+	}
+	sql, args, err := expr.Build(d.dialect())
+	if err != nil {
+		return clause.Column{}, false, err
+	}
+	sql, err = inlineArgs(d.DBType, sql, args)
+	if err != nil {
+		return clause.Column{}, false, err
+	}
+	return clause.Column{Name: sql, Raw: true}, desc, nil
+}
@@ -0,0 +1,85 @@
+package dac
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type dailyCount struct {
+	Day   string
+	Count int64
+}
+
+func TestGroupOrderExpr(t *testing.T) {
+	db, b := newFakeDB(t, Postgres)
+	day := DateTrunc("created_at", Day)
+	var out []dailyCount
+	err := NewDatabase(Postgres).Use(db).ServerVersion("16.2").Table("events").
+		SelectExpr(day.As("day"), Count(1, "count")).
+		GroupExpr(day).OrderExpr(Desc(day)).Find(&out).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts := b.statements()
+	if len(stmts) != 1 || !strings.Contains(stmts[0], `GROUP BY "day" ORDER BY "day" DESC`) {
+		t.Errorf("statements %q", stmts)
+	}
+}
+
+func TestGroupOrderString(t *testing.T) {
+	db, b := newFakeDB(t, Mysql)
+	var out []dailyCount
+	err := NewDatabase(Mysql).Use(db).Table("events").Select("day", Count(1, "count")).
+		Group("day").Order("day desc").Find(&out).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts := b.statements()
+	if len(stmts) != 1 || !strings.Contains(stmts[0], "GROUP BY `day` ORDER BY day desc") {
+		t.Errorf("statements %q", stmts)
+	}
+}
+
+func TestServerVersionDetected(t *testing.T) {
+	db, b := newFakeDB(t, Mysql)
+	b.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if query == "SELECT version()" {
+			return []string{"version()"}, [][]driver.Value{{"5.7.44-log"}}, nil
+		}
+		return nil, nil, nil
+	}
+	d := NewDatabase(Mysql).Use(db)
+	for i := 0; i < 3; i++ {
+		var out []dailyCount
+		err := d.WithContext(context.Background()).Table("events").
+			SelectExpr("day", RowNumber().OrderBy("day").As("rn")).Find(&out).Error()
+		if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "5.7.44") {
+			t.Errorf("err = %v, want ErrUnsupported for 5.7.44", err)
+		}
+	}
+	if n := b.count("SELECT version()"); n != 1 {
+		t.Errorf("version queries = %d, want 1", n)
+	}
+}
+
+func TestServerVersionNotDetected(t *testing.T) {
+	db, b := newFakeDB(t, Postgres)
+	var out []dailyCount
+	err := NewDatabase(Postgres).Use(db).Table("events").
+		SelectExpr(As(DateTrunc("created_at", Day), "day"), Count(1, "count")).
+		GroupExpr(DateTrunc("created_at", Day)).Find(&out).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cte := NewDatabase(Postgres).Use(db).Table("events").Select("id")
+	err = NewDatabase(Postgres).Use(db).With("recent", cte).Table("recent").Find(&out).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := b.count("SELECT version()"); n != 0 {
+		t.Errorf("version queries = %d, want 0, statements %q", n, b.statements())
+	}
+}
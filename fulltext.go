@@ -0,0 +1,177 @@
+package dac
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm/schema"
+)
+
+// DefaultTextSearchConfig Postgres 未指定语言时使用的全文检索配置
+// to_tsvector 必须指定配置才能建立 GIN 索引，查询和索引使用同一配置才能命中索引
+const DefaultTextSearchConfig = "simple"
+
+// FullTextQuery 全文检索的值，Language 为 Postgres 的检索配置，如 english
+// MySQL 的分词由索引的解析器决定，ClickHouse 按分词匹配，两者忽略 Language
+type FullTextQuery struct {
+	Text     string
+	Language string
+}
+
+// FullText 创建全文检索的值，用于 Match 操作符，Field 可以是逗号分隔的多个字段
+func FullText(text string, language ...string) FullTextQuery {
+	q := FullTextQuery{Text: text}
+	if len(language) > 0 {
+		q.Language = language[0]
+	}
+	return q
+}
+
+var languageRe = regexp.MustCompile(`^[A-Za-z_][\w.]*$`)
+
+// fullTextValue 解析 Match 的值，值可以是字符串或 FullTextQuery
+func fullTextValue(value interface{}) (FullTextQuery, error) {
+	var q FullTextQuery
+	switch v := value.(type) {
+	case string:
+		q.Text = v
+	case FullTextQuery:
+		q = v
+	default:
+		return q, fmt.Errorf("operator %s requires a string or FullTextQuery value, got %T", Match, value)
+	}
+	if q.Language != "" && !languageRe.MatchString(q.Language) {
+		return q, fmt.Errorf("invalid text search language %q", q.Language)
+	}
+	return q, nil
+}
+
+// fullTextColumns 拆分逗号分隔的字段
+func fullTextColumns(field string) []string {
+	var columns []string
+	for _, c := range strings.Split(field, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// tsvectorSQL 生成 Postgres 的 to_tsvector 表达式，查询条件和 GIN 索引共用
+func tsvectorSQL(language string, columns []string) string {
+	if language == "" {
+		language = DefaultTextSearchConfig
+	}
+	doc := columns[0]
+	if len(columns) > 1 {
+		parts := make([]string, len(columns))
+		for i, c := range columns {
+			parts[i] = fmt.Sprintf("coalesce(%s,'')", c)
+		}
+		doc = strings.Join(parts, " || ' ' || ")
+	}
+	return fmt.Sprintf("to_tsvector('%s', %s)", language, doc)
+}
+
+// isToken 判断文本是否为 ClickHouse hasToken 可以匹配的单个分词
+// hasToken 把 ASCII 字母、数字以外的 ASCII 字符（包括 _）都视为分隔符，非 ASCII 字符视为分词的一部分
+func isToken(s string) bool {
+	for _, r := range s {
+		if r < utf8.RuneSelf && !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// fullTextIndex AutoMigrate 根据 dac 标签创建的全文索引
+type fullTextIndex struct {
+	name     string
+	language string
+	columns  []string
+}
+
+// parseFullTextIndexes 解析模型中的全文索引，标签格式：
+// dac:"fulltext"、dac:"fulltext:idx_name"、dac:"fulltext:idx_name;language:english"，同名的字段合并为一个索引
+func parseFullTextIndexes(s *schema.Schema) []fullTextIndex {
+	indexes := map[string]*fullTextIndex{}
+	for _, field := range s.Fields {
+		tag := field.Tag.Get("dac")
+		if field.DBName == "" || !strings.HasPrefix(strings.ToLower(tag), "fulltext") {
+			continue
+		}
+		settings := schema.ParseTagSetting(tag, ";")
+		name := ""
+		if head := strings.SplitN(strings.SplitN(tag, ";", 2)[0], ":", 2); len(head) == 2 {
+			name = strings.TrimSpace(head[1])
+		}
+		if name == "" {
+			name = fmt.Sprintf("idx_%s_fulltext", s.Table)
+		}
+		idx, ok := indexes[name]
+		if !ok {
+			idx = &fullTextIndex{name: name}
+			indexes[name] = idx
+		}
+		if language := settings["LANGUAGE"]; language != "" {
+			idx.language = language
+		}
+		idx.columns = append(idx.columns, field.DBName)
+	}
+	result := make([]fullTextIndex, 0, len(indexes))
+	for _, idx := range indexes {
+		result = append(result, *idx)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
+
+// createFullTextIndexes 创建模型中声明的全文索引，已存在的索引跳过
+// MySQL 创建 FULLTEXT 索引；Postgres 在 to_tsvector 表达式上创建 GIN 索引；
+// ClickHouse 为每个字段添加 tokenbf_v1 跳数索引，用于加速 hasToken
+func (d *Database) createFullTextIndexes(model interface{}) error {
+	tx := d.getInstance()
+	s, err := schema.Parse(model, &sync.Map{}, tx.db.NamingStrategy)
+	if err != nil {
+		return err
+	}
+	for _, idx := range parseFullTextIndexes(s) {
+		if idx.language != "" && !languageRe.MatchString(idx.language) {
+			return fmt.Errorf("invalid text search language %q on index %s", idx.language, idx.name)
+		}
+		table := quoteIdentifier(tx.DBType, s.Table)
+		columns := make([]string, len(idx.columns))
+		for i, c := range idx.columns {
+			columns[i] = quoteIdentifier(tx.DBType, c)
+		}
+		var statements []string
+		switch tx.DBType {
+		case Mysql:
+			if tx.db.Migrator().HasIndex(model, idx.name) {
+				continue
+			}
+			statements = append(statements, fmt.Sprintf("CREATE FULLTEXT INDEX %s ON %s (%s)",
+				quoteIdentifier(tx.DBType, idx.name), table, strings.Join(columns, ",")))
+		case Postgres:
+			statements = append(statements, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN ((%s))",
+				quoteIdentifier(tx.DBType, idx.name), table, tsvectorSQL(idx.language, columns)))
+		case Clickhouse:
+			for i, c := range columns {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD INDEX IF NOT EXISTS %s %s TYPE tokenbf_v1(32768, 3, 0) GRANULARITY 1",
+					table, quoteIdentifier(tx.DBType, idx.name+"_"+idx.columns[i]), c))
+			}
+		default:
+			return fmt.Errorf("%w: fulltext index on %s", ErrUnsupported, tx.DBType)
+		}
+		for _, stmt := range statements {
+			if err := tx.db.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("create fulltext index %s: %w", idx.name, err)
+			}
+		}
+	}
+	return nil
+}
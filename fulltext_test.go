@@ -0,0 +1,62 @@
+package dac
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIsToken(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"error", true},
+		{"Error404", true},
+		{"日志", true},
+		{"", false},
+		{"user_id", false},
+		{"a-b", false},
+		{"a.b", false},
+	}
+	for _, tt := range tests {
+		if got := isToken(tt.s); got != tt.want {
+			t.Errorf("isToken(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		dbType DBType
+		field  string
+		value  interface{}
+		want   string
+		args   []interface{}
+	}{
+		{"mysql", Mysql, "title,body", "+go -java", "MATCH(title,body) AGAINST(? IN BOOLEAN MODE)", []interface{}{"+go -java"}},
+		{"postgres", Postgres, "title", "go", "to_tsvector('simple', title) @@ plainto_tsquery('simple', ?)", []interface{}{"go"}},
+		{"postgres columns", Postgres, "title,body", "go", "to_tsvector('simple', coalesce(title,'') || ' ' || coalesce(body,'')) @@ plainto_tsquery('simple', ?)", []interface{}{"go"}},
+		{"postgres language", Postgres, "title", FullText("go", "english"), "to_tsvector('english', title) @@ plainto_tsquery('english', ?)", []interface{}{"go"}},
+		{"clickhouse token", Clickhouse, "message", "timeout", "hasToken(message, ?)", []interface{}{"timeout"}},
+		{"clickhouse non-ascii token", Clickhouse, "message", "超时", "hasToken(message, ?)", []interface{}{"超时"}},
+		{"clickhouse underscore", Clickhouse, "message", "user_id", "multiSearchAny(message, [?])", []interface{}{"user_id"}},
+		{"clickhouse words", Clickhouse, "message", "disk full", "multiSearchAny(message, [?,?])", []interface{}{"disk", "full"}},
+		{"clickhouse columns", Clickhouse, "title,body", "timeout", "(multiSearchAny(title, [?]) OR multiSearchAny(body, [?]))", []interface{}{"timeout", "timeout"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewConditionBuilder().AppendCondition(tt.field, Match, tt.value)
+			query, args := cb.Build(tt.dbType)
+			if err := cb.Error(); err != nil {
+				t.Fatal(err)
+			}
+			if query != tt.want {
+				t.Errorf("query = %q, want %q", query, tt.want)
+			}
+			if fmt.Sprint(args) != fmt.Sprint(tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
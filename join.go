@@ -0,0 +1,90 @@
+package dac
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// JoinKind 连接类型
+type JoinKind string
+
+const (
+	JoinInner JoinKind = "INNER JOIN"
+	JoinLeft  JoinKind = "LEFT JOIN"
+	JoinRight JoinKind = "RIGHT JOIN"
+	JoinFull  JoinKind = "FULL JOIN" // MySQL 不支持
+	JoinCross JoinKind = "CROSS JOIN"
+	// 以下仅 ClickHouse 支持
+	JoinAny      JoinKind = "ANY INNER JOIN" // 右表每个键只取一行
+	JoinAnyLeft  JoinKind = "ANY LEFT JOIN"
+	JoinAsof     JoinKind = "ASOF JOIN" // ON 中最后一个条件须为不等比较，取最接近的一行
+	JoinAsofLeft JoinKind = "ASOF LEFT JOIN"
+)
+
+var identifierRe = regexp.MustCompile(`^[A-Za-z_][\w]*(\.[A-Za-z_][\w]*)?$`)
+
+// JoinTable 连接 ti 对应的表，ON 条件由 on 生成，条件的字段为 alias.column 时按数据库加引号
+// 值为 Column、ColumnOf 时作为列与列的比较；CROSS JOIN 的 on 须为 nil
+func (d *Database) JoinTable(kind JoinKind, ti TableInfo, on *ConditionBuilder) *Database {
+	tx := d.getInstance()
+	if err := checkJoinKind(tx.DBType, kind); err != nil {
+		tx.err = err
+		return tx
+	}
+	table := quoteIdentifier(tx.DBType, ti.TableName())
+	if alias := ti.TableAlias(); alias != "" {
+		table += " AS " + quoteIdentifier(tx.DBType, alias)
+	}
+	if kind == JoinCross {
+		if on != nil && len(on.conditions) > 0 {
+			tx.err = fmt.Errorf("%s does not take an ON condition", kind)
+			return tx
+		}
+		return tx.useSourceDB(tx.db.Joins(fmt.Sprintf("%s %s", kind, table)))
+	}
+	if on == nil || len(on.conditions) == 0 {
+		tx.err = fmt.Errorf("%s %s requires an ON condition", kind, ti.TableName())
+		return tx
+	}
+	query, args, err := buildOnCondition(tx.DBType, on)
+	if err != nil {
+		tx.err = err
+		return tx
+	}
+	return tx.useSourceDB(tx.db.Joins(fmt.Sprintf("%s %s ON %s", kind, table, query), args...))
+}
+
+// checkJoinKind 检查数据库是否支持该连接类型
+func checkJoinKind(dbType DBType, kind JoinKind) error {
+	switch kind {
+	case JoinInner, JoinLeft, JoinRight, JoinCross:
+		return nil
+	case JoinFull:
+		if dbType == Mysql {
+			return fmt.Errorf("%w: %s on %s", ErrUnsupported, kind, dbType)
+		}
+		return nil
+	case JoinAny, JoinAnyLeft, JoinAsof, JoinAsofLeft:
+		if dbType != Clickhouse {
+			return fmt.Errorf("%w: %s on %s", ErrUnsupported, kind, dbType)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown join kind %q", kind)
+}
+
+// buildOnCondition 生成 ON 条件，简单的字段名加引号，其他表达式原样保留
+func buildOnCondition(dbType DBType, on *ConditionBuilder) (string, []interface{}, error) {
+	quoted := &ConditionBuilder{conditions: make([]Condition, len(on.conditions))}
+	for i, c := range on.conditions {
+		if identifierRe.MatchString(c.Field) {
+			c.Field = quoteIdentifier(dbType, c.Field)
+		}
+		quoted.conditions[i] = c
+	}
+	query, args := quoted.Build(dbType)
+	if err := quoted.Error(); err != nil {
+		return "", nil, err
+	}
+	return query, args, nil
+}
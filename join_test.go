@@ -0,0 +1,142 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type joinOrder struct{}
+
+func (joinOrder) TableName() string  { return "orders" }
+func (joinOrder) TableAlias() string { return "o" }
+
+func TestJoinTable(t *testing.T) {
+	on := func() *ConditionBuilder {
+		return NewConditionBuilder().
+			AppendCondition("o.user_id", Equal, ColumnOf(columnUser{}, "id")).
+			AppendCondition("o.amount", GreaterThan, 100)
+	}
+	tests := []struct {
+		dbType DBType
+		kind   JoinKind
+		want   string
+		err    error
+	}{
+		{Mysql, JoinInner, "SELECT * FROM users AS u INNER JOIN `orders` AS `o` ON `o`.`user_id` = `u`.`id` AND `o`.`amount` > ?", nil},
+		{Mysql, JoinLeft, "SELECT * FROM users AS u LEFT JOIN `orders` AS `o` ON `o`.`user_id` = `u`.`id` AND `o`.`amount` > ?", nil},
+		{Mysql, JoinRight, "SELECT * FROM users AS u RIGHT JOIN `orders` AS `o` ON `o`.`user_id` = `u`.`id` AND `o`.`amount` > ?", nil},
+		{Mysql, JoinFull, "", ErrUnsupported},
+		{Mysql, JoinAny, "", ErrUnsupported},
+		{Postgres, JoinFull, `SELECT * FROM users AS u FULL JOIN "orders" AS "o" ON "o"."user_id" = "u"."id" AND "o"."amount" > $1`, nil},
+		{Postgres, JoinAsof, "", ErrUnsupported},
+		{Clickhouse, JoinFull, "SELECT * FROM users AS u FULL JOIN `orders` AS `o` ON `o`.`user_id` = `u`.`id`  AND `o`.`amount` > ?", nil},
+		{Clickhouse, JoinAny, "SELECT * FROM users AS u ANY INNER JOIN `orders` AS `o` ON `o`.`user_id` = `u`.`id`  AND `o`.`amount` > ?", nil},
+		{Clickhouse, JoinAnyLeft, "SELECT * FROM users AS u ANY LEFT JOIN `orders` AS `o` ON `o`.`user_id` = `u`.`id`  AND `o`.`amount` > ?", nil},
+		{Clickhouse, JoinAsof, "SELECT * FROM users AS u ASOF JOIN `orders` AS `o` ON `o`.`user_id` = `u`.`id`  AND `o`.`amount` > ?", nil},
+		{Clickhouse, JoinAsofLeft, "SELECT * FROM users AS u ASOF LEFT JOIN `orders` AS `o` ON `o`.`user_id` = `u`.`id`  AND `o`.`amount` > ?", nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.dbType)+"/"+string(tt.kind), func(t *testing.T) {
+			db, b := newFakeDB(t, tt.dbType)
+			var args []driver.NamedValue
+			b.query = func(query string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+				args = a
+				return nil, nil, nil
+			}
+			var out []map[string]interface{}
+			err := NewDatabase(tt.dbType).Use(db).Table("users AS u").JoinTable(tt.kind, joinOrder{}, on()).Find(&out).Error()
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stmts := b.statements(); len(stmts) != 1 || stmts[0] != tt.want {
+				t.Errorf("statements %q, want %q", stmts, tt.want)
+			}
+			if len(args) != 1 || args[0].Value != int64(100) {
+				t.Errorf("args %v, want [100]", args)
+			}
+		})
+	}
+}
+
+func TestJoinTableErrors(t *testing.T) {
+	on := NewConditionBuilder().AppendCondition("o.user_id", Equal, Column("u.id"))
+	tests := []struct {
+		name string
+		kind JoinKind
+		on   *ConditionBuilder
+		want string
+	}{
+		{"unknown kind", JoinKind("OUTER APPLY"), on, "unknown join kind"},
+		{"missing on", JoinInner, nil, "requires an ON condition"},
+		{"empty on", JoinLeft, NewConditionBuilder(), "requires an ON condition"},
+		{"cross join with on", JoinCross, on, "does not take an ON condition"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := newFakeDB(t, Postgres)
+			var out []map[string]interface{}
+			err := NewDatabase(Postgres).Use(db).Table("users AS u").JoinTable(tt.kind, joinOrder{}, tt.on).Find(&out).Error()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinTableCross(t *testing.T) {
+	db, b := newFakeDB(t, Mysql)
+	var out []map[string]interface{}
+	if err := NewDatabase(Mysql).Use(db).Table("users").JoinTable(JoinCross, columnOrder{}, nil).Find(&out).Error(); err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT * FROM `users` CROSS JOIN `orders`"
+	if stmts := b.statements(); len(stmts) != 1 || stmts[0] != want {
+		t.Errorf("statements %q, want %q", stmts, want)
+	}
+}
+
+func TestBuildOnConditionKeepsExpressions(t *testing.T) {
+	on := NewConditionBuilder().
+		AppendCondition("lower(o.email)", Equal, Column("u.email")).
+		AppendCondition("o.deleted_at", IsNull, nil)
+	query, args, err := buildOnCondition(Postgres, on)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `lower(o.email) = ? AND "o"."deleted_at" IS NULL`; query != want {
+		t.Errorf("query = %q, want %q", query, want)
+	}
+	if len(args) != 1 {
+		t.Errorf("args %v, want the column expression only", args)
+	}
+}
+
+func TestJoins(t *testing.T) {
+	db, b := newFakeDB(t, Mysql)
+	var args []driver.NamedValue
+	b.query = func(query string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		args = a
+		return nil, nil, nil
+	}
+	var out []map[string]interface{}
+	err := NewDatabase(Mysql).Use(db).Table("users AS u").
+		Joins("JOIN orders AS o ON o.user_id = u.id AND o.status = ?", "paid").Find(&out).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT * FROM users AS u JOIN orders AS o ON o.user_id = u.id AND o.status = ?"
+	if stmts := b.statements(); len(stmts) != 1 || stmts[0] != want {
+		t.Errorf("statements %q, want %q", stmts, want)
+	}
+	if len(args) != 1 || args[0].Value != "paid" {
+		t.Errorf("args %v, want [paid]", args)
+	}
+}
@@ -0,0 +1,273 @@
+package dac
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSONPathSeparator Condition.Field 中字段与 JSON 路径的分隔符，如 attrs->$.color、attrs->color.name、attrs->items[0].id
+// attrs.color 的写法需要先通过 RegisterJSONColumn 注册 attrs，否则无法与 table.column 区分
+const JSONPathSeparator = "->"
+
+var jsonColumns sync.Map
+
+// RegisterJSONColumn 注册 JSON 列，之后 Condition.Field 可以使用 attrs.color、o.attrs.items[0].id 的写法
+func RegisterJSONColumn(columns ...string) {
+	for _, column := range columns {
+		jsonColumns.Store(column, struct{}{})
+	}
+}
+
+// JSONPath 生成 JSON 路径字段，keys 为对象的键（string）或数组下标（int），
+// 如 JSONPath("attrs", "items", 0, "id") 为 attrs->$.items[0].id
+func JSONPath(column string, keys ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(column + JSONPathSeparator + "$")
+	for _, k := range keys {
+		if n, ok := k.(int); ok {
+			b.WriteString("[" + strconv.Itoa(n) + "]")
+		} else {
+			b.WriteString("." + fmt.Sprint(k))
+		}
+	}
+	return b.String()
+}
+
+// normalizeJSONField 将已注册 JSON 列的 attrs.color 写法转换为 attrs->color，其他字段原样返回
+func normalizeJSONField(field string) string {
+	if isJSONPath(field) {
+		return field
+	}
+	parts := strings.SplitN(field, ".", 3)
+	for i := 0; i < len(parts)-1 && i < 2; i++ {
+		column := strings.TrimSpace(parts[i])
+		if _, ok := jsonColumns.Load(column); ok {
+			prefix := strings.Join(parts[:i+1], ".")
+			return prefix + JSONPathSeparator + field[len(prefix)+1:]
+		}
+	}
+	return field
+}
+
+// jsonPath 解析后的 JSON 字段路径
+type jsonPath struct {
+	column string
+	keys   []interface{} // string 为对象的键，int 为数组下标（从 0 开始）
+}
+
+var (
+	jsonSegmentRe = regexp.MustCompile(`^([\w-]*)((?:\[\d+\])*)$`)
+	jsonIndexRe   = regexp.MustCompile(`\[(\d+)\]`)
+)
+
+// isJSONPath 判断字段是否包含 JSON 路径
+func isJSONPath(field string) bool {
+	return strings.Contains(field, JSONPathSeparator)
+}
+
+// parseJSONPath 解析 column->path，path 可以省略开头的 $.
+func parseJSONPath(field string) (jsonPath, error) {
+	i := strings.Index(field, JSONPathSeparator)
+	p := jsonPath{column: strings.TrimSpace(field[:i])}
+	path := strings.TrimSpace(field[i+len(JSONPathSeparator):])
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if p.column == "" || path == "" {
+		return p, fmt.Errorf("invalid json path %q", field)
+	}
+	for _, segment := range strings.Split(path, ".") {
+		match := jsonSegmentRe.FindStringSubmatch(segment)
+		if match == nil || (match[1] == "" && match[2] == "") {
+			return p, fmt.Errorf("invalid json path %q: unsupported segment %q", field, segment)
+		}
+		if match[1] != "" {
+			p.keys = append(p.keys, match[1])
+		}
+		for _, idx := range jsonIndexRe.FindAllStringSubmatch(match[2], -1) {
+			n, _ := strconv.Atoi(idx[1])
+			p.keys = append(p.keys, n)
+		}
+	}
+	return p, nil
+}
+
+// mysqlPath MySQL 的路径，如 $.items[0].id
+func (p jsonPath) mysqlPath() string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, k := range p.keys {
+		switch v := k.(type) {
+		case string:
+			// 包含 - 的键需要加引号
+			if strings.Contains(v, "-") {
+				b.WriteString(`."` + v + `"`)
+			} else {
+				b.WriteString("." + v)
+			}
+		case int:
+			b.WriteString("[" + strconv.Itoa(v) + "]")
+		}
+	}
+	return b.String()
+}
+
+// postgresPath Postgres 的路径数组，如 {items,0,id}
+func (p jsonPath) postgresPath() string {
+	parts := make([]string, len(p.keys))
+	for i, k := range p.keys {
+		parts[i] = fmt.Sprint(k)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// clickhouseArgs ClickHouse JSONExtract* 的路径参数，数组下标从 1 开始
+func (p jsonPath) clickhouseArgs() string {
+	parts := make([]string, len(p.keys))
+	for i, k := range p.keys {
+		switch v := k.(type) {
+		case string:
+			parts[i] = "'" + v + "'"
+		case int:
+			parts[i] = strconv.Itoa(v + 1)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// jsonValueKind 根据条件的值决定 JSON 字段提取后的类型
+func jsonValueKind(value interface{}) reflect.Kind {
+	v := reflect.ValueOf(value)
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Slice || v.Kind() == reflect.Array) {
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+			continue
+		}
+		if v.Len() == 0 {
+			return reflect.String
+		}
+		v = v.Index(0)
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+	}
+	if !v.IsValid() {
+		return reflect.String
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.Int64
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	case reflect.Bool:
+		return reflect.Bool
+	}
+	return reflect.String
+}
+
+// extract 生成提取 JSON 字段并转换为值对应类型的表达式
+func (p jsonPath) extract(dbType DBType, kind reflect.Kind) string {
+	switch dbType {
+	case Postgres:
+		sql := fmt.Sprintf("(%s#>>'%s')", p.column, p.postgresPath())
+		if len(p.keys) == 1 {
+			if key, ok := p.keys[0].(string); ok {
+				sql = fmt.Sprintf("(%s->>'%s')", p.column, key)
+			}
+		}
+		switch kind {
+		case reflect.Int64:
+			return sql + "::bigint"
+		case reflect.Float64:
+			return sql + "::double precision"
+		case reflect.Bool:
+			return sql + "::boolean"
+		}
+		return sql
+	case Clickhouse:
+		fn := "JSONExtractString"
+		switch kind {
+		case reflect.Int64:
+			fn = "JSONExtractInt"
+		case reflect.Float64:
+			fn = "JSONExtractFloat"
+		case reflect.Bool:
+			fn = "JSONExtractBool"
+		}
+		return fmt.Sprintf("%s(%s, %s)", fn, p.column, p.clickhouseArgs())
+	default:
+		switch kind {
+		case reflect.Int64, reflect.Float64:
+			// 数字直接与 JSON 数值比较
+			return fmt.Sprintf("JSON_EXTRACT(%s, '%s')", p.column, p.mysqlPath())
+		}
+		// 布尔值在 buildJSONCondition 中转换为 'true'、'false' 与去掉引号的文本比较
+		return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '%s'))", p.column, p.mysqlPath())
+	}
+}
+
+// buildJSONCondition 将 JSON 路径字段的条件改写为对应数据库的提取表达式
+// JSONContains、JSONHasKey 由各数据库的操作符自行解析路径
+func buildJSONCondition(dbType DBType, condition *Condition) error {
+	if field := normalizeJSONField(condition.Field); field != condition.Field {
+		condition.Field = field
+		condition.Key = field
+	}
+	switch condition.Operator {
+	case JSONContains:
+		doc, err := json.Marshal(condition.Value)
+		if err != nil {
+			return fmt.Errorf("marshal %s value: %w", JSONContains, err)
+		}
+		condition.Value = string(doc)
+		if isJSONPath(condition.Field) {
+			_, err = parseJSONPath(condition.Field)
+		}
+		return err
+	case JSONHasKey:
+		_, err := parseJSONPath(condition.Field)
+		return err
+	}
+	if !isJSONPath(condition.Field) {
+		return nil
+	}
+	p, err := parseJSONPath(condition.Field)
+	if err != nil {
+		return err
+	}
+	kind := jsonValueKind(condition.Value)
+	condition.Key = p.extract(dbType, kind)
+	if dbType == Mysql && kind == reflect.Bool {
+		condition.Value = jsonBoolText(condition.Value)
+	}
+	return nil
+}
+
+// jsonBoolText 将布尔值转换为 JSON 文本，用于 MySQL 的比较
+func jsonBoolText(value interface{}) interface{} {
+	v := reflect.Indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Slice, reflect.Array:
+		texts := make([]string, v.Len())
+		for i := range texts {
+			texts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return texts
+	}
+	return value
+}
+
+// jsonContainsTarget 解析 JSONContains 的字段，返回 JSON 列和可选的路径
+func jsonContainsTarget(field string) (column string, path jsonPath, hasPath bool) {
+	if !isJSONPath(field) {
+		return field, jsonPath{}, false
+	}
+	p, _ := parseJSONPath(field)
+	return p.column, p, true
+}
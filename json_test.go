@@ -0,0 +1,52 @@
+package dac
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONPathCondition(t *testing.T) {
+	RegisterJSONColumn("attrs")
+	tests := []struct {
+		name   string
+		dbType DBType
+		field  string
+		value  interface{}
+		want   string
+	}{
+		{"mysql dot", Mysql, "attrs.color", "red", "JSON_UNQUOTE(JSON_EXTRACT(attrs, '$.color')) = ?"},
+		{"mysql arrow", Mysql, "attrs->$.color", "red", "JSON_UNQUOTE(JSON_EXTRACT(attrs, '$.color')) = ?"},
+		{"mysql helper", Mysql, JSONPath("attrs", "items", 0, "id"), 3, "JSON_EXTRACT(attrs, '$.items[0].id') = ?"},
+		{"postgres dot", Postgres, "attrs.color", "red", "(attrs->>'color') = ?"},
+		{"postgres qualified", Postgres, "o.attrs.size", 3, "(o.attrs->>'size')::bigint = ?"},
+		{"clickhouse dot", Clickhouse, "attrs.color", "red", "JSONExtractString(attrs, 'color') = ?"},
+		{"not registered", Mysql, "orders.color", "red", "orders.color = ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewConditionBuilder()
+			cb.AppendCondition(tt.field, Equal, tt.value)
+			sql, args := cb.Build(tt.dbType)
+			if err := cb.Error(); err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(sql) != tt.want {
+				t.Errorf("sql = %q, want %q", sql, tt.want)
+			}
+			if !reflect.DeepEqual(args, []interface{}{tt.value}) {
+				t.Errorf("args = %v", args)
+			}
+		})
+	}
+}
+
+func TestClickhouseJSONContainsUnsupported(t *testing.T) {
+	cb := NewConditionBuilder()
+	cb.AppendCondition("attrs", JSONContains, map[string]string{"color": "red"})
+	cb.Build(Clickhouse)
+	if err := cb.Error(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("err = %v, want ErrUnsupported", err)
+	}
+}
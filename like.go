@@ -0,0 +1,58 @@
+package dac
+
+import (
+	"fmt"
+	"strings"
+)
+
+// likeEscape LIKE 的转义字符，ClickHouse 的 LIKE 不支持 ESCAPE 子句，固定使用反斜杠；
+// MySQL、Postgres 使用 ! 并显式声明 ESCAPE，避免受 NO_BACKSLASH_ESCAPES、standard_conforming_strings 影响
+func likeEscape(dbType DBType) string {
+	if dbType == Clickhouse {
+		return `\`
+	}
+	return "!"
+}
+
+// escapeLike 转义值中的 %、_ 和转义字符，使其在 LIKE 中按字面匹配
+func escapeLike(dbType DBType, s string) string {
+	esc := likeEscape(dbType)
+	return strings.NewReplacer(esc, esc+esc, "%", esc+"%", "_", esc+"_").Replace(s)
+}
+
+// likeClause LIKE 模式后的 ESCAPE 子句
+func likeClause(dbType DBType) string {
+	if dbType == Clickhouse {
+		return ""
+	}
+	return " ESCAPE '" + likeEscape(dbType) + "'"
+}
+
+// buildStringCondition 转义 Contains、StartsWith、EndsWith、ILike、IEqual 的值并添加通配符
+// Like、NotLike 的值作为模式原样使用，不能传入不可信的输入
+func buildStringCondition(dbType DBType, condition *Condition) error {
+	switch condition.Operator {
+	case Contains, StartsWith, EndsWith, ILike, IEqual:
+	default:
+		return nil
+	}
+	s, ok := condition.Value.(string)
+	if !ok {
+		return fmt.Errorf("operator %s requires a string value, got %T", condition.Operator, condition.Value)
+	}
+	// ClickHouse 的 ILike 使用 positionCaseInsensitive，MySQL 的 IEqual 使用 =，都不需要转义
+	if (condition.Operator == ILike && dbType == Clickhouse) || (condition.Operator == IEqual && dbType == Mysql) {
+		return nil
+	}
+	s = escapeLike(dbType, s)
+	switch condition.Operator {
+	case Contains, ILike:
+		s = "%" + s + "%"
+	case StartsWith:
+		s += "%"
+	case EndsWith:
+		s = "%" + s
+	}
+	condition.Value = s
+	return nil
+}
@@ -0,0 +1,53 @@
+package dac
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStringOperators(t *testing.T) {
+	tests := []struct {
+		name     string
+		dbType   DBType
+		operator Operator
+		value    string
+		wantSQL  string
+		wantArg  string
+	}{
+		{"mysql contains", Mysql, Contains, "50%_off!", "name LIKE ? ESCAPE '!'", "%50!%!_off!!%"},
+		{"mysql starts with", Mysql, StartsWith, "a_b", "name LIKE ? ESCAPE '!'", "a!_b%"},
+		{"mysql ilike", Mysql, ILike, "A%", "LOWER(name) LIKE LOWER(?) ESCAPE '!'", "%A!%%"},
+		{"mysql iequal", Mysql, IEqual, "A%", "", "A%"},
+		{"postgres ends with", Postgres, EndsWith, "x%", `name LIKE ? ESCAPE '!'`, "%x!%"},
+		{"postgres ilike", Postgres, ILike, "a_b", `name ILIKE ? ESCAPE '!'`, "%a!_b%"},
+		{"postgres iequal", Postgres, IEqual, "a%", `name ILIKE ? ESCAPE '!'`, "a!%"},
+		{"clickhouse contains", Clickhouse, Contains, `50%\`, "name LIKE ?", `%50\%\\%`},
+		{"clickhouse ilike", Clickhouse, ILike, "a%", "", "a%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewConditionBuilder()
+			cb.AppendCondition("name", tt.operator, tt.value)
+			sql, args := cb.Build(tt.dbType)
+			if err := cb.Error(); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantSQL != "" && strings.TrimSpace(sql) != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, []interface{}{tt.wantArg}) {
+				t.Errorf("args = %q, want [%q]", args, tt.wantArg)
+			}
+		})
+	}
+}
+
+func TestStringOperatorRequiresString(t *testing.T) {
+	cb := NewConditionBuilder()
+	cb.AppendCondition("name", Contains, 1)
+	cb.Build(Mysql)
+	if cb.Error() == nil {
+		t.Error("expected an error for a non-string value")
+	}
+}
@@ -0,0 +1,122 @@
+package dac
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrStaleObject 乐观锁检查失败，行已被其他人修改或删除
+var ErrStaleObject = errors.New("stale object")
+
+// StaleObjectError 乐观锁检查失败的行，errors.Is(err, ErrStaleObject) 为 true
+type StaleObjectError struct {
+	Table   string
+	Version interface{} // 更新时使用的旧版本号
+}
+
+func (e *StaleObjectError) Error() string {
+	return fmt.Sprintf("%s: %s version %v has been modified", ErrStaleObject, e.Table, e.Version)
+}
+
+func (e *StaleObjectError) Is(target error) bool {
+	return target == ErrStaleObject
+}
+
+// versionLock 一次带版本检查的更新
+type versionLock struct {
+	table string
+	field *schema.Field
+	rv    reflect.Value
+	old   interface{}
+}
+
+// versionField 获取 dac:"version" 标记的版本字段，字段须为整数类型
+func versionField(s *schema.Schema) *schema.Field {
+	for _, field := range s.Fields {
+		if _, ok := schema.ParseTagSetting(field.Tag.Get("dac"), ";")["VERSION"]; ok {
+			return field
+		}
+	}
+	return nil
+}
+
+// lockVersion Save、Updates 时添加 WHERE version = 旧版本号并将版本号加 1，创建时版本号为零值则设为 1
+// 模型没有版本字段时返回 nil；ClickHouse 不支持，可以使用 ReplacingMergeTree 的版本列代替
+func (d *Database) lockVersion(op operation, value interface{}) (*versionLock, error) {
+	s, table := d.target(value)
+	if s == nil {
+		return nil, nil
+	}
+	field := versionField(s)
+	if field == nil {
+		return nil, nil
+	}
+	if d.DBType == Clickhouse && op != opCreate {
+		return nil, fmt.Errorf("%w: optimistic locking on %s, use a ReplacingMergeTree version column", ErrUnsupported, d.DBType)
+	}
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	if rv.Kind() == reflect.Map {
+		// 按 map 更新时由调用方自行处理版本号
+		return nil, nil
+	}
+	if rv.Kind() != reflect.Struct {
+		if op == opCreate {
+			return nil, eachStruct(value, func(rv reflect.Value) error {
+				return initVersion(d, field, rv)
+			})
+		}
+		return nil, fmt.Errorf("optimistic locking on %s requires a single struct, got %T", table, value)
+	}
+	if op == opCreate {
+		return nil, initVersion(d, field, rv)
+	}
+	switch field.FieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return nil, fmt.Errorf("version field %s of %s must be an integer, got %s", field.Name, s.Name, field.FieldType)
+	}
+	ctx := d.db.Statement.Context
+	old, _ := field.ValueOf(ctx, rv)
+	next := reflect.ValueOf(old).Convert(reflect.TypeOf(int64(0))).Int() + 1
+	if err := field.Set(ctx, rv, next); err != nil {
+		return nil, err
+	}
+	d.db = d.db.Where(fmt.Sprintf("%s = ?", quoteIdentifier(d.DBType, table+"."+field.DBName)), old)
+	return &versionLock{table: table, field: field, rv: rv, old: old}, nil
+}
+
+func initVersion(d *Database, field *schema.Field, rv reflect.Value) error {
+	ctx := d.db.Statement.Context
+	if _, zero := field.ValueOf(ctx, rv); zero {
+		return field.Set(ctx, rv, 1)
+	}
+	return nil
+}
+
+// check 更新后检查影响行数，失败时恢复旧版本号
+func (l *versionLock) check(db *gorm.DB) error {
+	if db.Error == nil && (db.RowsAffected > 0 || db.DryRun) {
+		return nil
+	}
+	_ = l.field.Set(db.Statement.Context, l.rv, l.old)
+	if db.Error != nil {
+		return db.Error
+	}
+	return &StaleObjectError{Table: l.table, Version: l.old}
+}
+
+// versionColumnSelected 更新的列中是否包含版本字段，Select 了部分列时需要加上版本列
+func versionColumnSelected(selects []string, column string) bool {
+	for _, s := range selects {
+		if s == "*" || strings.EqualFold(s, column) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type lockedRow struct {
+	ID      uint
+	Name    string
+	Version int64 `dac:"version"`
+}
+
+func TestOptimisticLock(t *testing.T) {
+	tests := []struct {
+		name        string
+		affected    int64
+		run         func(d *Database, row *lockedRow) error
+		wantStale   bool
+		wantVersion int64
+	}{
+		{"save", 1, func(d *Database, row *lockedRow) error { return d.Save(row).Error() }, false, 4},
+		{"save stale", 0, func(d *Database, row *lockedRow) error { return d.Save(row).Error() }, true, 3},
+		{"updates", 1, func(d *Database, row *lockedRow) error { return d.Updates(row).Error() }, false, 4},
+		{"updates stale", 0, func(d *Database, row *lockedRow) error { return d.Updates(row).Error() }, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, b := newFakeDB(t, Mysql)
+			b.exec = func(query string, args []driver.NamedValue) (int64, error) {
+				return tt.affected, nil
+			}
+			row := &lockedRow{ID: 1, Name: "a", Version: 3}
+			err := tt.run(NewDatabase(Mysql).Use(db), row)
+			var stale *StaleObjectError
+			if tt.wantStale {
+				if !errors.Is(err, ErrStaleObject) || !errors.As(err, &stale) || stale.Version != int64(3) {
+					t.Errorf("err = %v, want ErrStaleObject for version 3", err)
+				}
+				if ClassifyError(Mysql, err) != ErrorClassStaleObject {
+					t.Errorf("class = %s", ClassifyError(Mysql, err))
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if row.Version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", row.Version, tt.wantVersion)
+			}
+			stmts := b.statements()
+			if len(stmts) != 1 || !strings.Contains(stmts[0], "`locked_rows`.`version` = ?") {
+				t.Errorf("statements %q", stmts)
+			}
+		})
+	}
+}
+
+func TestOptimisticLockCreateInitializesVersion(t *testing.T) {
+	db, _ := newFakeDB(t, Mysql)
+	row := &lockedRow{Name: "a"}
+	if err := NewDatabase(Mysql).Use(db).Create(row).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if row.Version != 1 {
+		t.Errorf("version = %d, want 1", row.Version)
+	}
+}
@@ -0,0 +1,320 @@
+package dac
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrorClass 错误分类，用于指标和重试判断
+type ErrorClass string
+
+const (
+	ErrorClassNone          ErrorClass = ""
+	ErrorClassNotFound      ErrorClass = "not_found"
+	ErrorClassDuplicateKey  ErrorClass = "duplicate_key"
+	ErrorClassStaleObject   ErrorClass = "stale_object"
+	ErrorClassTenant        ErrorClass = "tenant_required"
+	ErrorClassUnsupported   ErrorClass = "unsupported"
+	ErrorClassTimeout       ErrorClass = "timeout"
+	ErrorClassCanceled      ErrorClass = "canceled"
+	ErrorClassDeadlock      ErrorClass = "deadlock"
+	ErrorClassSerialization ErrorClass = "serialization" // PostgreSQL 可串行化事务冲突
+	ErrorClassOverloaded    ErrorClass = "overloaded"    // 连接数或并发查询数超过上限
+	ErrorClassConnection    ErrorClass = "connection"
+	ErrorClassOther         ErrorClass = "other"
+)
+
+// QueryMetric 一次语句执行的指标
+type QueryMetric struct {
+	DBType       DBType
+	Operation    string // find、first、count、create、update 等，与方法名对应
+	Table        string
+	Fingerprint  string // 去掉参数值后的 SQL，见 Fingerprint
+	Duration     time.Duration
+	RowsAffected int64
+	ErrorClass   ErrorClass
+	Err          error
+}
+
+// MetricsRecorder 接收语句执行的指标，可以适配 Prometheus、OpenTelemetry 等
+// RecordQuery 在执行语句的 goroutine 中同步调用，实现需要并发安全且不应阻塞
+type MetricsRecorder interface {
+	RecordQuery(m QueryMetric)
+}
+
+var (
+	metricsMu       sync.RWMutex
+	metricsRecorder MetricsRecorder
+)
+
+// RegisterMetricsRecorder 设置默认的指标接收者，为 nil 时不记录
+func RegisterMetricsRecorder(recorder MetricsRecorder) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	metricsRecorder = recorder
+}
+
+func getMetricsRecorder() MetricsRecorder {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	return metricsRecorder
+}
+
+// Metrics 设置当前实例的指标接收者，覆盖 RegisterMetricsRecorder 设置的默认值
+func (d *Database) Metrics(recorder MetricsRecorder) *Database {
+	tx := d.getInstance()
+	tx.metrics = recorder
+	return tx
+}
+
+func (d *Database) metricsRecorder() MetricsRecorder {
+	if d.metrics != nil {
+		return d.metrics
+	}
+	return getMetricsRecorder()
+}
+
+// observe 记录语句的指标和慢查询，在方法开始时调用，返回的函数在方法返回前调用：
+// defer tx.observe("find", out)()
+func (d *Database) observe(name string, value interface{}) func() {
+	recorder := d.metricsRecorder()
+	slow := getSlowQueryConfig(d.DBType)
+	if recorder == nil && slow == nil {
+		return func() {}
+	}
+	// 复用的语句上可能保存着上一次执行的 SQL
+	d.db.Statement.Settings.Delete(executedSQLKey)
+	start := time.Now()
+	return func() {
+		err := d.err
+		if err == nil {
+			err = d.db.Error
+		}
+		d.finish(recorder, slow, name, value, time.Since(start), d.db, err)
+	}
+}
+
+// executedSQLKey 保存执行的语句的 Statement.Settings 键
+const executedSQLKey = "dac:executed_sql"
+
+// executedSQL 执行的 SQL 和参数，gorm 执行完成后会清空 Statement.SQL 和 Vars，需要在回调中保存
+type executedSQL struct {
+	sql  string
+	vars []interface{}
+}
+
+var captureMu sync.Mutex
+
+// registerCapture 在 gorm 的各个执行回调之后保存 SQL 和参数，同一个 db 只注册一次
+func registerCapture(db *gorm.DB) {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+	if db.Callback().Query().Get("dac:capture") != nil {
+		return
+	}
+	capture := func(db *gorm.DB) {
+		db.Statement.Settings.Store(executedSQLKey, executedSQL{sql: db.Statement.SQL.String(), vars: db.Statement.Vars})
+	}
+	_ = db.Callback().Create().After("gorm:create").Register("dac:capture", capture)
+	_ = db.Callback().Query().After("gorm:query").Register("dac:capture", capture)
+	_ = db.Callback().Update().After("gorm:update").Register("dac:capture", capture)
+	_ = db.Callback().Delete().After("gorm:delete").Register("dac:capture", capture)
+	_ = db.Callback().Row().After("gorm:row").Register("dac:capture", capture)
+	_ = db.Callback().Raw().After("gorm:raw").Register("dac:capture", capture)
+}
+
+// lastSQL 语句最近一次执行的 SQL 和参数，未执行时为空
+func lastSQL(db *gorm.DB) (string, []interface{}) {
+	if v, ok := db.Statement.Settings.Load(executedSQLKey); ok {
+		e := v.(executedSQL)
+		return e.sql, e.vars
+	}
+	return "", nil
+}
+
+// finish 语句执行完成后记录指标，超过阈值时记录慢查询
+func (d *Database) finish(recorder MetricsRecorder, slow *SlowQueryConfig, name string, value interface{}, duration time.Duration, db *gorm.DB, err error) {
+	table := d.tableTag(value)
+	if recorder != nil {
+		m := QueryMetric{
+			DBType:     d.DBType,
+			Operation:  name,
+			Table:      table,
+			Duration:   duration,
+			ErrorClass: ClassifyError(d.DBType, err),
+			Err:        err,
+		}
+		if db != nil {
+			m.RowsAffected = db.RowsAffected
+			sql, _ := lastSQL(db)
+			m.Fingerprint = Fingerprint(sql)
+		}
+		recorder.RecordQuery(m)
+	}
+	if slow != nil && db != nil {
+		d.logSlowQuery(slow, name, table, duration, db, err)
+	}
+}
+
+var (
+	fingerprintStringRe = regexp.MustCompile(`'(?:[^']|'')*'`)
+	fingerprintNumberRe = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	fingerprintParamRe  = regexp.MustCompile(`\$\d+`)
+	fingerprintListRe   = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)(?:\s*,\s*\(\s*\?(?:\s*,\s*\?)*\s*\))*`)
+	fingerprintSpaceRe  = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint 归一化 SQL，字面量和占位符替换为 ?，IN 列表和多行 VALUES 合并为 (...)，
+// 参数个数不同的同一语句得到相同的结果
+func Fingerprint(sql string) string {
+	sql = fingerprintStringRe.ReplaceAllString(sql, "?")
+	sql = fingerprintParamRe.ReplaceAllString(sql, "?")
+	sql = fingerprintNumberRe.ReplaceAllString(sql, "?")
+	sql = fingerprintListRe.ReplaceAllString(sql, "(...)")
+	sql = fingerprintSpaceRe.ReplaceAllString(sql, " ")
+	return strings.TrimSpace(sql)
+}
+
+// ClassifyError 按数据库类型对错误分类，err 为 nil 时返回 ErrorClassNone
+func ClassifyError(dbType DBType, err error) ErrorClass {
+	if err == nil {
+		return ErrorClassNone
+	}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrorClassNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrorClassDuplicateKey
+	case errors.Is(err, ErrStaleObject):
+		return ErrorClassStaleObject
+	case errors.Is(err, ErrTenantRequired):
+		return ErrorClassTenant
+	case errors.Is(err, ErrUnsupported):
+		return ErrorClassUnsupported
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return ErrorClassConnection
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTimeout
+	}
+	msg := err.Error()
+	for _, c := range dialectErrorClasses[dbType] {
+		if strings.Contains(msg, c.match) {
+			return c.class
+		}
+	}
+	switch {
+	case strings.Contains(msg, "connection reset"), strings.Contains(msg, "connection refused"),
+		strings.Contains(msg, "broken pipe"), strings.Contains(msg, "bad connection"):
+		return ErrorClassConnection
+	}
+	return ErrorClassOther
+}
+
+// dialectErrorClasses 各数据库驱动错误信息中的错误码
+var dialectErrorClasses = map[DBType][]struct {
+	match string
+	class ErrorClass
+}{
+	Mysql: {
+		{"Error 1062", ErrorClassDuplicateKey},
+		{"Error 1213", ErrorClassDeadlock},
+		{"Error 1205", ErrorClassTimeout},    // Lock wait timeout exceeded
+		{"Error 3024", ErrorClassTimeout},    // max_execution_time
+		{"Error 1040", ErrorClassOverloaded}, // Too many connections
+		{"Error 2006", ErrorClassConnection},
+		{"Error 2013", ErrorClassConnection},
+		{"invalid connection", ErrorClassConnection},
+	},
+	Postgres: {
+		{"SQLSTATE 23505", ErrorClassDuplicateKey},
+		{"SQLSTATE 40P01", ErrorClassDeadlock},
+		{"SQLSTATE 40001", ErrorClassSerialization},
+		{"SQLSTATE 57014", ErrorClassTimeout},    // statement_timeout
+		{"SQLSTATE 55P03", ErrorClassTimeout},    // lock_timeout
+		{"SQLSTATE 53300", ErrorClassOverloaded}, // too_many_connections
+		{"SQLSTATE 08", ErrorClassConnection},
+		{"SQLSTATE 57P01", ErrorClassConnection}, // admin_shutdown
+		{"conn closed", ErrorClassConnection},
+	},
+	Clickhouse: {
+		{"code: 159,", ErrorClassTimeout},    // TIMEOUT_EXCEEDED
+		{"code: 209,", ErrorClassTimeout},    // SOCKET_TIMEOUT
+		{"code: 202,", ErrorClassOverloaded}, // TOO_MANY_SIMULTANEOUS_QUERIES
+		{"code: 210,", ErrorClassConnection},
+		{"code: 394,", ErrorClassCanceled}, // QUERY_WAS_CANCELLED
+	},
+}
+
+// MemoryMetrics 保存在内存中的指标，用于测试
+type MemoryMetrics struct {
+	mu      sync.Mutex
+	records []QueryMetric
+}
+
+// NewMemoryMetrics 创建内存指标
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{}
+}
+
+func (m *MemoryMetrics) RecordQuery(metric QueryMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, metric)
+}
+
+// Records 已记录的指标
+func (m *MemoryMetrics) Records() []QueryMetric {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]QueryMetric(nil), m.records...)
+}
+
+// Count 操作的执行次数，operation 为空时统计所有操作
+func (m *MemoryMetrics) Count(operation string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, r := range m.records {
+		if operation == "" || r.Operation == operation {
+			n++
+		}
+	}
+	return n
+}
+
+// Errors 各错误分类的次数
+func (m *MemoryMetrics) Errors() map[ErrorClass]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	errs := make(map[ErrorClass]int)
+	for _, r := range m.records {
+		if r.ErrorClass != ErrorClassNone {
+			errs[r.ErrorClass]++
+		}
+	}
+	return errs
+}
+
+// Reset 清空已记录的指标
+func (m *MemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = nil
+}
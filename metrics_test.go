@@ -0,0 +1,94 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+type metricRow struct {
+	ID   uint
+	Name string
+}
+
+func (metricRow) TableName() string  { return "metric_rows" }
+func (metricRow) TableAlias() string { return "" }
+
+func TestMemoryMetrics(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         func(d *Database) error
+		operation   string
+		fingerprint string
+		rows        int64
+		class       ErrorClass
+	}{
+		{
+			name: "find",
+			run: func(d *Database) error {
+				var rows []metricRow
+				return d.Query("name IN (?)", []string{"a", "b"}).Find(&rows).Error()
+			},
+			operation:   "find",
+			fingerprint: "SELECT * FROM `metric_rows` WHERE name IN (...)",
+			rows:        2,
+		},
+		{
+			name: "update",
+			run: func(d *Database) error {
+				return d.Model(&metricRow{ID: 1}).Update("name", "x").Error()
+			},
+			operation:   "update",
+			fingerprint: "UPDATE `metric_rows` SET `name`=? WHERE `id` = ?",
+			rows:        1,
+		},
+		{
+			name: "duplicate key",
+			run: func(d *Database) error {
+				return d.Create(&metricRow{ID: 9, Name: "dup"}).Error()
+			},
+			operation:   "create",
+			fingerprint: "INSERT INTO `metric_rows` (`name`,`id`) VALUES (...)",
+			class:       ErrorClassDuplicateKey,
+		},
+		{
+			name: "raw exec",
+			run: func(d *Database) error {
+				return d.Exec("DELETE FROM metric_rows WHERE name = 'old' AND id > 10").Error()
+			},
+			operation:   "exec",
+			fingerprint: "DELETE FROM metric_rows WHERE name = ? AND id > ?",
+			rows:        1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, b := newFakeDB(t, Mysql)
+			b.exec = func(query string, args []driver.NamedValue) (int64, error) {
+				if tt.class == ErrorClassDuplicateKey {
+					return 0, errors.New("Error 1062: Duplicate entry '9' for key 'PRIMARY'")
+				}
+				return 1, nil
+			}
+			b.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+				return []string{"id", "name"}, [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}}, nil
+			}
+			metrics := NewMemoryMetrics()
+			err := tt.run(NewDatabase(Mysql).Use(db).Metrics(metrics))
+			if (err != nil) != (tt.class != ErrorClassNone) {
+				t.Fatalf("err = %v", err)
+			}
+			records := metrics.Records()
+			if len(records) != 1 {
+				t.Fatalf("records %+v, want 1", records)
+			}
+			m := records[0]
+			if m.Operation != tt.operation || m.Fingerprint != tt.fingerprint || m.RowsAffected != tt.rows || m.ErrorClass != tt.class {
+				t.Errorf("metric %+v, want operation %s, fingerprint %q, rows %d, class %q", m, tt.operation, tt.fingerprint, tt.rows, tt.class)
+			}
+			if tt.operation != "exec" && m.Table != "metric_rows" {
+				t.Errorf("table = %q, want metric_rows", m.Table)
+			}
+		})
+	}
+}
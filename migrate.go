@@ -0,0 +1,529 @@
+package dac
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// MigrationTable 默认的迁移记录表
+const MigrationTable = "dac_schema_migrations"
+
+// ErrMigrationOutOfOrder 存在版本号小于已执行版本的待执行迁移
+var ErrMigrationOutOfOrder = errors.New("migration out of order")
+
+// Migration 版本化迁移，可以是 Go 函数，也可以是按数据库类型区分的 SQL 脚本
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      func(tx *Database) error
+	Down    func(tx *Database) error
+
+	upSQL   map[DBType]string // key 为空表示通用脚本
+	downSQL map[DBType]string
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version   uint64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// MigrationStep 迁移计划中的一步，用于 DryRun
+type MigrationStep struct {
+	Version uint64
+	Name    string
+	Down    bool
+	SQL     []string // Go 函数迁移时为空
+}
+
+type migrationRecord struct {
+	Version   uint64    `gorm:"column:version"`
+	Name      string    `gorm:"column:name"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+var registeredMigrations []*Migration
+
+// RegisterMigration 注册 Go 函数形式的迁移
+func RegisterMigration(version uint64, name string, up, down func(tx *Database) error) {
+	registeredMigrations = append(registeredMigrations, &Migration{Version: version, Name: name, Up: up, Down: down})
+}
+
+// migrationFileRe 匹配 0003_add_index[.mysql][.up|.down].sql
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([^.]+)((?:\.[a-z]+)*)\.sql$`)
+
+// LoadMigrations 从目录中加载 SQL 迁移脚本
+// 文件名格式为 <版本>_<名称>[.<数据库类型>][.up|.down].sql，未指定方向时视为 up
+func LoadMigrations(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[uint64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		var dbType DBType
+		down := false
+		for _, part := range strings.Split(strings.TrimPrefix(match[3], "."), ".") {
+			switch part {
+			case "":
+			case "up":
+			case "down":
+				down = true
+			case string(Mysql), string(Postgres), string(Clickhouse):
+				dbType = DBType(part)
+			default:
+				return nil, fmt.Errorf("unknown suffix %q in migration file %s", part, entry.Name())
+			}
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2], upSQL: map[DBType]string{}, downSQL: map[DBType]string{}}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %s and %s", version, m.Name, match[2])
+		}
+		if down {
+			m.downSQL[dbType] = string(content)
+		} else {
+			m.upSQL[dbType] = string(content)
+		}
+	}
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// script 获取指定数据库类型的脚本，优先使用数据库专属脚本
+func (m *Migration) script(dbType DBType, down bool) (string, bool) {
+	scripts := m.upSQL
+	if down {
+		scripts = m.downSQL
+	}
+	if s, ok := scripts[dbType]; ok {
+		return s, true
+	}
+	s, ok := scripts[""]
+	return s, ok
+}
+
+func (m *Migration) run(tx *Database, down bool) error {
+	fn := m.Up
+	if down {
+		fn = m.Down
+	}
+	if fn != nil {
+		return fn(tx)
+	}
+	script, ok := m.script(tx.DBType, down)
+	if !ok {
+		if down {
+			return fmt.Errorf("migration %d_%s has no down script for %s", m.Version, m.Name, tx.DBType)
+		}
+		return fmt.Errorf("migration %d_%s has no up script for %s", m.Version, m.Name, tx.DBType)
+	}
+	for _, stmt := range splitStatements(script) {
+		if err := tx.DB().Session(&gorm.Session{NewDB: true}).Exec(stmt).Error; err != nil {
+			return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db         *Database
+	table      string
+	migrations []*Migration
+}
+
+// NewMigrator 创建迁移执行器，未传入迁移时使用 RegisterMigration 注册的迁移
+func NewMigrator(d *Database, migrations ...*Migration) *Migrator {
+	if len(migrations) == 0 {
+		migrations = registeredMigrations
+	}
+	sorted := make([]*Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: d, table: MigrationTable, migrations: sorted}
+}
+
+// Table 设置迁移记录表名
+func (m *Migrator) Table(name string) *Migrator {
+	m.table = name
+	return m
+}
+
+// Up 执行全部待执行的迁移
+func (m *Migrator) Up() error {
+	return m.withLock(func(tx *Database) error {
+		applied, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		pending, err := m.pending(applied)
+		if err != nil {
+			return err
+		}
+		for _, mg := range pending {
+			if err := m.apply(tx, mg, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down 回滚最近执行的 steps 个迁移
+func (m *Migrator) Down(steps int) error {
+	return m.withLock(func(tx *Database) error {
+		applied, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		targets, err := m.rollbackTargets(applied, steps)
+		if err != nil {
+			return err
+		}
+		for _, mg := range targets {
+			if err := m.apply(tx, mg, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status 获取所有迁移的执行状态，记录表不存在时所有迁移均为未执行，不修改数据库
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.appliedIfExists(m.session())
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		s := MigrationStatus{Version: mg.Version, Name: mg.Name}
+		if r, ok := applied[mg.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = r.AppliedAt
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// DryRun 返回 Up(steps 为 0) 或 Down(steps 大于 0) 将要执行的步骤，不修改数据库
+func (m *Migrator) DryRun(steps int) ([]MigrationStep, error) {
+	applied, err := m.appliedIfExists(m.session())
+	if err != nil {
+		return nil, err
+	}
+	var targets []*Migration
+	down := steps > 0
+	if down {
+		targets, err = m.rollbackTargets(applied, steps)
+	} else {
+		targets, err = m.pending(applied)
+	}
+	if err != nil {
+		return nil, err
+	}
+	plan := make([]MigrationStep, 0, len(targets))
+	for _, mg := range targets {
+		step := MigrationStep{Version: mg.Version, Name: mg.Name, Down: down}
+		fn := mg.Up
+		if down {
+			fn = mg.Down
+		}
+		if fn == nil {
+			script, ok := mg.script(m.db.DBType, down)
+			if !ok {
+				return nil, fmt.Errorf("migration %d_%s has no script for %s", mg.Version, mg.Name, m.db.DBType)
+			}
+			step.SQL = splitStatements(script)
+		}
+		plan = append(plan, step)
+	}
+	return plan, nil
+}
+
+// pending 获取待执行的迁移，存在乱序迁移时返回 ErrMigrationOutOfOrder
+func (m *Migrator) pending(applied map[uint64]migrationRecord) ([]*Migration, error) {
+	var maxApplied uint64
+	for v := range applied {
+		if v > maxApplied {
+			maxApplied = v
+		}
+	}
+	var pending []*Migration
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+		if mg.Version < maxApplied {
+			return nil, fmt.Errorf("%w: %d_%s is pending but %d is already applied", ErrMigrationOutOfOrder, mg.Version, mg.Name, maxApplied)
+		}
+		pending = append(pending, mg)
+	}
+	return pending, nil
+}
+
+// rollbackTargets 获取需要回滚的迁移，按版本倒序
+func (m *Migrator) rollbackTargets(applied map[uint64]migrationRecord, steps int) ([]*Migration, error) {
+	known := map[uint64]*Migration{}
+	for _, mg := range m.migrations {
+		known[mg.Version] = mg
+	}
+	versions := make([]uint64, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	var targets []*Migration
+	for _, v := range versions {
+		if len(targets) >= steps {
+			break
+		}
+		mg, ok := known[v]
+		if !ok {
+			return nil, fmt.Errorf("applied migration %d is not registered", v)
+		}
+		targets = append(targets, mg)
+	}
+	return targets, nil
+}
+
+// appliedIfExists 读取已执行的迁移，记录表不存在时返回空，不创建记录表
+func (m *Migrator) appliedIfExists(tx *Database) (map[uint64]migrationRecord, error) {
+	if !tx.DB().Session(&gorm.Session{NewDB: true}).Migrator().HasTable(m.table) {
+		return map[uint64]migrationRecord{}, nil
+	}
+	return m.applied(tx)
+}
+
+func (m *Migrator) applied(tx *Database) (map[uint64]migrationRecord, error) {
+	var records []migrationRecord
+	if err := tx.DB().Session(&gorm.Session{NewDB: true}).Table(m.table).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[uint64]migrationRecord, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// apply 执行单个迁移并更新记录表，PostgreSQL 支持事务性 DDL，因此放在事务中执行
+func (m *Migrator) apply(tx *Database, mg *Migration, down bool) error {
+	run := func(tx *Database) error {
+		if err := mg.run(tx, down); err != nil {
+			return err
+		}
+		if down {
+			return m.deleteRecord(tx, mg.Version)
+		}
+		return tx.DB().Session(&gorm.Session{NewDB: true}).Table(m.table).
+			Create(&migrationRecord{Version: mg.Version, Name: mg.Name, AppliedAt: time.Now()}).Error
+	}
+	if tx.DBType != Postgres {
+		return run(tx)
+	}
+	return tx.DB().Transaction(func(gtx *gorm.DB) error {
+		return run(NewDatabase(tx.DBType).Use(gtx))
+	})
+}
+
+func (m *Migrator) deleteRecord(tx *Database, version uint64) error {
+	db := tx.DB().Session(&gorm.Session{NewDB: true})
+	if tx.DBType == Clickhouse {
+		return db.Exec(fmt.Sprintf("ALTER TABLE %s DELETE WHERE version = ?", m.table), version).Error
+	}
+	return db.Exec(fmt.Sprintf("DELETE FROM %s WHERE version = ?", m.table), version).Error
+}
+
+func (m *Migrator) ensureTable(tx *Database) error {
+	var ddl string
+	switch tx.DBType {
+	case Mysql:
+		ddl = "CREATE TABLE IF NOT EXISTS %s (version BIGINT UNSIGNED NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at DATETIME NOT NULL)"
+	case Postgres:
+		ddl = "CREATE TABLE IF NOT EXISTS %s (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)"
+	case Clickhouse:
+		ddl = "CREATE TABLE IF NOT EXISTS %s (version UInt64, name String, applied_at DateTime) ENGINE = MergeTree ORDER BY version"
+	default:
+		return fmt.Errorf("migration is not supported on %s", tx.DBType)
+	}
+	return tx.DB().Session(&gorm.Session{NewDB: true}).Exec(fmt.Sprintf(ddl, m.table)).Error
+}
+
+func (m *Migrator) session() *Database {
+	return NewDatabase(m.db.DBType).Use(m.db.DB().Session(&gorm.Session{NewDB: true}))
+}
+
+// clickhouseMigrationLock ClickHouse 没有会话级的咨询锁，只能保证进程内串行
+// 多个实例同时部署时不受保护，需要由部署流程保证只有一个实例执行迁移
+var clickhouseMigrationLock sync.Mutex
+
+// withLock 在独占连接上加锁后执行迁移
+// MySQL 使用 GET_LOCK，PostgreSQL 使用 pg_advisory_lock，ClickHouse 仅在进程内加锁，见 clickhouseMigrationLock
+func (m *Migrator) withLock(fn func(tx *Database) error) error {
+	return m.db.DB().Session(&gorm.Session{NewDB: true}).Connection(func(conn *gorm.DB) error {
+		tx := NewDatabase(m.db.DBType).Use(conn)
+		unlock, err := m.lock(tx)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		if err := m.ensureTable(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+func (m *Migrator) lock(tx *Database) (func(), error) {
+	key := "dac_migrate_" + m.table
+	db := tx.DB()
+	switch tx.DBType {
+	case Mysql:
+		var got int
+		if err := db.Raw("SELECT GET_LOCK(?, ?)", key, 60).Scan(&got).Error; err != nil {
+			return nil, err
+		}
+		if got != 1 {
+			return nil, fmt.Errorf("failed to acquire migration lock %s", key)
+		}
+		return func() { db.Exec("SELECT RELEASE_LOCK(?)", key) }, nil
+	case Postgres:
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		id := int64(h.Sum64())
+		if err := db.Exec("SELECT pg_advisory_lock(?)", id).Error; err != nil {
+			return nil, err
+		}
+		return func() { db.Exec("SELECT pg_advisory_unlock(?)", id) }, nil
+	default:
+		clickhouseMigrationLock.Lock()
+		return clickhouseMigrationLock.Unlock, nil
+	}
+}
+
+// splitStatements 按分号拆分 SQL 脚本，忽略引号、注释和 PostgreSQL $$ 函数体中的分号
+// 行注释会被去掉，块注释保留在语句中，MySQL 的 /*! */ 和优化器提示依赖块注释
+func splitStatements(script string) []string {
+	var (
+		stmts        []string
+		current      strings.Builder
+		quote        rune
+		lineComment  bool
+		blockComment bool
+		dollarTag    string
+	)
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case lineComment:
+			if r == '\n' {
+				lineComment = false
+				current.WriteRune(r)
+			}
+			continue
+		case blockComment:
+			if r == '*' && i+1 < len(runes) && runes[i+1] == '/' {
+				blockComment = false
+				current.WriteString("*/")
+				i++
+				continue
+			}
+		case dollarTag != "":
+			if strings.HasPrefix(string(runes[i:]), dollarTag) {
+				current.WriteString(dollarTag)
+				i += len([]rune(dollarTag)) - 1
+				dollarTag = ""
+				continue
+			}
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			lineComment = true
+			continue
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			blockComment = true
+			current.WriteString("/*")
+			i++
+			continue
+		case r == '$':
+			if tag := dollarQuoteTag(runes[i:]); tag != "" && (i == 0 || !isIdentRune(runes[i-1])) {
+				dollarTag = tag
+				current.WriteString(tag)
+				i += len([]rune(tag)) - 1
+				continue
+			}
+		case r == ';':
+			if s := strings.TrimSpace(current.String()); s != "" {
+				stmts = append(stmts, s)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if s := strings.TrimSpace(current.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+// dollarQuoteTag 解析 PostgreSQL 的 $tag$ 开始标记，不是开始标记时返回空
+func dollarQuoteTag(runes []rune) string {
+	for i := 1; i < len(runes); i++ {
+		r := runes[i]
+		if r == '$' {
+			return string(runes[:i+1])
+		}
+		if !isIdentRune(r) || (i == 1 && r >= '0' && r <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
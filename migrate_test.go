@@ -0,0 +1,88 @@
+package dac
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "plain",
+			script: "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);",
+			want:   []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:   "quotes and line comment",
+			script: "INSERT INTO a VALUES ('x;y'); -- drop; here\nSELECT \"c;d\";",
+			want:   []string{"INSERT INTO a VALUES ('x;y')", "SELECT \"c;d\""},
+		},
+		{
+			name:   "block comment",
+			script: "/* setup; first */ CREATE TABLE a (id INT); SELECT /*+ MAX_EXECUTION_TIME(1) */ 1;",
+			want:   []string{"/* setup; first */ CREATE TABLE a (id INT)", "SELECT /*+ MAX_EXECUTION_TIME(1) */ 1"},
+		},
+		{
+			name: "dollar quoted function body",
+			script: `CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER t BEFORE UPDATE ON a FOR EACH ROW EXECUTE FUNCTION touch();`,
+			want: []string{
+				"CREATE FUNCTION touch() RETURNS trigger AS $$\nBEGIN\n  NEW.updated_at = now();\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql",
+				"CREATE TRIGGER t BEFORE UPDATE ON a FOR EACH ROW EXECUTE FUNCTION touch()",
+			},
+		},
+		{
+			name:   "tagged dollar quote containing $$",
+			script: "DO $body$ BEGIN PERFORM '$$;'; END; $body$; SELECT 1;",
+			want:   []string{"DO $body$ BEGIN PERFORM '$$;'; END; $body$", "SELECT 1"},
+		},
+		{
+			name:   "positional parameter is not a dollar quote",
+			script: "PREPARE p AS SELECT $1; EXECUTE p(1);",
+			want:   []string{"PREPARE p AS SELECT $1", "EXECUTE p(1)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigratorReadOnlyWithoutTable(t *testing.T) {
+	db, backend := newFakeDB(t, Mysql)
+	m := NewMigrator(NewDatabase(Mysql).Use(db),
+		&Migration{Version: 1, Name: "init", upSQL: map[DBType]string{"": "CREATE TABLE a (id INT)"}})
+
+	status, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 || status[0].Applied {
+		t.Errorf("Status() = %+v, want one pending migration", status)
+	}
+	plan, err := m.DryRun(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 1 || !reflect.DeepEqual(plan[0].SQL, []string{"CREATE TABLE a (id INT)"}) {
+		t.Errorf("DryRun() = %+v", plan)
+	}
+	for _, stmt := range backend.statements() {
+		if strings.HasPrefix(stmt, "CREATE") {
+			t.Errorf("Status/DryRun modified the database: %s", stmt)
+		}
+	}
+}
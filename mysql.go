@@ -1,6 +1,9 @@
 package dac
 
 import (
+	"fmt"
+	"strings"
+
 	"gorm.io/gorm"
 )
 
@@ -36,8 +39,50 @@ func (m MysqlOperator) BuildQuery(condition Condition, qf *QueryFilter) {
 		m.GreaterThanOrEqual(condition, qf)
 	case LessThanOrEqual:
 		m.LessThanOrEqual(condition, qf)
+	case LessThan:
+		m.LessThan(condition, qf)
 	case In:
 		m.In(condition, qf)
+	case NotIn:
+		m.NotIn(condition, qf)
+	case IsNull:
+		m.IsNull(condition, qf)
+	case IsNotNull:
+		m.IsNotNull(condition, qf)
+	case Between:
+		m.Between(condition, qf)
+	case NotBetween:
+		m.NotBetween(condition, qf)
+	case Exists:
+		m.Exists(condition, qf)
+	case NotExists:
+		m.NotExists(condition, qf)
+	case Match:
+		m.Match(condition, qf)
+	case JSONContains:
+		m.JSONContains(condition, qf)
+	case JSONHasKey:
+		m.JSONHasKey(condition, qf)
+	case ArrayContains:
+		m.ArrayContains(condition, qf)
+	case ArrayContainsAll:
+		m.ArrayContainsAll(condition, qf)
+	case ArrayOverlaps:
+		m.ArrayOverlaps(condition, qf)
+	case ArrayLength:
+		m.ArrayLength(condition, qf)
+	case Like:
+		m.Like(condition, qf)
+	case NotLike:
+		m.NotLike(condition, qf)
+	case Contains, StartsWith, EndsWith:
+		m.Contains(condition, qf)
+	case ILike:
+		m.ILike(condition, qf)
+	case IEqual:
+		m.IEqual(condition, qf)
+	default:
+		qf.Err = fmt.Errorf("operator %s is not supported on %s", condition.Operator, Mysql)
 	}
 }
 func (m MysqlOperator) GreaterThanOrEqual(condition Condition, qf *QueryFilter) {
@@ -49,6 +94,9 @@ func (m MysqlOperator) GreaterThan(condition Condition, qf *QueryFilter) {
 func (m MysqlOperator) LessThanOrEqual(condition Condition, qf *QueryFilter) {
 	qf.And(condition.Key+" <= ?", condition.Value)
 }
+func (m MysqlOperator) LessThan(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" < ?", condition.Value)
+}
 
 func (m MysqlOperator) Equal(condition Condition, qf *QueryFilter) {
 	qf.And(condition.Key+" = ?", condition.Value)
@@ -59,3 +107,100 @@ func (m MysqlOperator) NotEqual(condition Condition, qf *QueryFilter) {
 func (m MysqlOperator) In(condition Condition, qf *QueryFilter) {
 	qf.And(condition.Key+" in (?)", condition.Value)
 }
+func (m MysqlOperator) NotIn(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" not in (?)", condition.Value)
+}
+
+// Match 全文检索，字段须建有 FULLTEXT 索引，使用布尔模式，文本中可以使用 +、-、* 等操作符
+func (m MysqlOperator) Match(condition Condition, qf *QueryFilter) {
+	q, _ := fullTextValue(condition.Value)
+	qf.And(fmt.Sprintf("MATCH(%s) AGAINST(? IN BOOLEAN MODE)", strings.Join(fullTextColumns(condition.Key), ",")), q.Text)
+}
+
+// JSONContains JSON_CONTAINS，值已序列化为 JSON 文本
+func (m MysqlOperator) JSONContains(condition Condition, qf *QueryFilter) {
+	column, path, hasPath := jsonContainsTarget(condition.Key)
+	if hasPath {
+		qf.And(fmt.Sprintf("JSON_CONTAINS(%s, ?, '%s')", column, path.mysqlPath()), condition.Value)
+		return
+	}
+	qf.And(fmt.Sprintf("JSON_CONTAINS(%s, ?)", column), condition.Value)
+}
+func (m MysqlOperator) JSONHasKey(condition Condition, qf *QueryFilter) {
+	p, _ := parseJSONPath(condition.Key)
+	qf.And(fmt.Sprintf("JSON_CONTAINS_PATH(%s, 'one', '%s')", p.column, p.mysqlPath()))
+}
+
+// ArrayContains 字段为 JSON 数组，值已序列化为 JSON
+func (m MysqlOperator) ArrayContains(condition Condition, qf *QueryFilter) {
+	qf.And(fmt.Sprintf("JSON_CONTAINS(%s, ?)", condition.Key), condition.Value)
+}
+
+// ArrayContainsAll 每个元素分别判断，空切片恒为真
+func (m MysqlOperator) ArrayContainsAll(condition Condition, qf *QueryFilter) {
+	docs := condition.Value.([]interface{})
+	if len(docs) == 0 {
+		return
+	}
+	parts := make([]string, len(docs))
+	for i := range docs {
+		parts[i] = fmt.Sprintf("JSON_CONTAINS(%s, ?)", condition.Key)
+	}
+	qf.And("("+strings.Join(parts, " AND ")+")", docs...)
+}
+
+// ArrayOverlaps 使用 JSON_CONTAINS 的 OR 模拟，兼容没有 JSON_OVERLAPS 的版本，空切片恒为假
+func (m MysqlOperator) ArrayOverlaps(condition Condition, qf *QueryFilter) {
+	docs := condition.Value.([]interface{})
+	if len(docs) == 0 {
+		qf.And("1 = 0")
+		return
+	}
+	parts := make([]string, len(docs))
+	for i := range docs {
+		parts[i] = fmt.Sprintf("JSON_CONTAINS(%s, ?)", condition.Key)
+	}
+	qf.And("("+strings.Join(parts, " OR ")+")", docs...)
+}
+func (m MysqlOperator) ArrayLength(condition Condition, qf *QueryFilter) {
+	qf.And(fmt.Sprintf("JSON_LENGTH(%s) = ?", condition.Key), condition.Value)
+}
+func (m MysqlOperator) Like(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" LIKE ?", condition.Value)
+}
+func (m MysqlOperator) NotLike(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" NOT LIKE ?", condition.Value)
+}
+
+// Contains 同时用于 StartsWith、EndsWith，值已转义并添加通配符，大小写是否敏感取决于字段的排序规则
+func (m MysqlOperator) Contains(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" LIKE ?"+likeClause(Mysql), condition.Value)
+}
+
+// ILike 使用 LOWER，字段为 _ci 排序规则时可以直接使用 Contains 以利用索引
+func (m MysqlOperator) ILike(condition Condition, qf *QueryFilter) {
+	qf.And("LOWER("+condition.Key+") LIKE LOWER(?)"+likeClause(Mysql), condition.Value)
+}
+func (m MysqlOperator) IEqual(condition Condition, qf *QueryFilter) {
+	qf.And("LOWER("+condition.Key+") = LOWER(?)", condition.Value)
+}
+func (m MysqlOperator) IsNull(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key + " IS NULL")
+}
+func (m MysqlOperator) IsNotNull(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key + " IS NOT NULL")
+}
+func (m MysqlOperator) Between(condition Condition, qf *QueryFilter) {
+	low, high, _ := betweenValues(condition.Value)
+	qf.And(condition.Key+" BETWEEN ? AND ?", low, high)
+}
+func (m MysqlOperator) NotBetween(condition Condition, qf *QueryFilter) {
+	low, high, _ := betweenValues(condition.Value)
+	qf.And(condition.Key+" NOT BETWEEN ? AND ?", low, high)
+}
+func (m MysqlOperator) Exists(condition Condition, qf *QueryFilter) {
+	qf.And("EXISTS (?)", condition.Value)
+}
+func (m MysqlOperator) NotExists(condition Condition, qf *QueryFilter) {
+	qf.And("NOT EXISTS (?)", condition.Value)
+}
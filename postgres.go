@@ -1,5 +1,7 @@
 package dac
 
+import "fmt"
+
 type PostgresDatabase struct {
 	DataAccess
 	PostgresOperator
@@ -15,6 +17,56 @@ func (m PostgresOperator) BuildQuery(condition Condition, qf *QueryFilter) {
 		m.Equal(condition, qf)
 	case NotEqual:
 		m.NotEqual(condition, qf)
+	case GreaterThan:
+		m.GreaterThan(condition, qf)
+	case GreaterThanOrEqual:
+		m.GreaterThanOrEqual(condition, qf)
+	case LessThan:
+		m.LessThan(condition, qf)
+	case LessThanOrEqual:
+		m.LessThanOrEqual(condition, qf)
+	case In:
+		m.In(condition, qf)
+	case NotIn:
+		m.NotIn(condition, qf)
+	case IsNull:
+		m.IsNull(condition, qf)
+	case IsNotNull:
+		m.IsNotNull(condition, qf)
+	case Between:
+		m.Between(condition, qf)
+	case NotBetween:
+		m.NotBetween(condition, qf)
+	case Exists:
+		m.Exists(condition, qf)
+	case NotExists:
+		m.NotExists(condition, qf)
+	case Match:
+		m.Match(condition, qf)
+	case JSONContains:
+		m.JSONContains(condition, qf)
+	case JSONHasKey:
+		m.JSONHasKey(condition, qf)
+	case ArrayContains:
+		m.ArrayContains(condition, qf)
+	case ArrayContainsAll:
+		m.ArrayContainsAll(condition, qf)
+	case ArrayOverlaps:
+		m.ArrayOverlaps(condition, qf)
+	case ArrayLength:
+		m.ArrayLength(condition, qf)
+	case Like:
+		m.Like(condition, qf)
+	case NotLike:
+		m.NotLike(condition, qf)
+	case Contains, StartsWith, EndsWith:
+		m.Contains(condition, qf)
+	case ILike:
+		m.ILike(condition, qf)
+	case IEqual:
+		m.IEqual(condition, qf)
+	default:
+		qf.Err = fmt.Errorf("operator %s is not supported on %s", condition.Operator, Postgres)
 	}
 }
 
@@ -24,6 +76,113 @@ func (m PostgresOperator) Equal(condition Condition, qf *QueryFilter) {
 func (m PostgresOperator) NotEqual(condition Condition, qf *QueryFilter) {
 	qf.And(condition.Key+" != ?", condition.Value)
 }
+func (m PostgresOperator) GreaterThan(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" > ?", condition.Value)
+}
+func (m PostgresOperator) GreaterThanOrEqual(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" >= ?", condition.Value)
+}
+func (m PostgresOperator) LessThan(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" < ?", condition.Value)
+}
+func (m PostgresOperator) LessThanOrEqual(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" <= ?", condition.Value)
+}
+func (m PostgresOperator) In(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" in (?)", condition.Value)
+}
+func (m PostgresOperator) NotIn(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" not in (?)", condition.Value)
+}
+
+// Match 全文检索，多个字段拼接后分词，与 AutoMigrate 创建的 GIN 索引表达式一致
+func (m PostgresOperator) Match(condition Condition, qf *QueryFilter) {
+	q, _ := fullTextValue(condition.Value)
+	language := q.Language
+	if language == "" {
+		language = DefaultTextSearchConfig
+	}
+	qf.And(fmt.Sprintf("%s @@ plainto_tsquery('%s', ?)", tsvectorSQL(language, fullTextColumns(condition.Key)), language), q.Text)
+}
+
+// JSONContains 使用 @>，字段须为 jsonb 类型
+func (m PostgresOperator) JSONContains(condition Condition, qf *QueryFilter) {
+	column, path, hasPath := jsonContainsTarget(condition.Key)
+	if hasPath {
+		column = fmt.Sprintf("(%s#>'%s')", column, path.postgresPath())
+	}
+	qf.And(column+" @> ?::jsonb", condition.Value)
+}
+
+// JSONHasKey 使用 jsonb_path_exists，字段须为 jsonb 类型，需要 Postgres 12
+func (m PostgresOperator) JSONHasKey(condition Condition, qf *QueryFilter) {
+	p, _ := parseJSONPath(condition.Key)
+	qf.And(fmt.Sprintf("jsonb_path_exists(%s, '%s')", p.column, p.mysqlPath()))
+}
+func (m PostgresOperator) ArrayContains(condition Condition, qf *QueryFilter) {
+	qf.And(fmt.Sprintf("? = ANY(%s)", condition.Key), condition.Value)
+}
+
+// ArrayContainsAll 空切片恒为真
+func (m PostgresOperator) ArrayContainsAll(condition Condition, qf *QueryFilter) {
+	elements := condition.Value.(postgresArray)
+	if len(elements) == 0 {
+		return
+	}
+	qf.And(condition.Key+" @> ?", elements)
+}
+
+// ArrayOverlaps 空切片恒为假
+func (m PostgresOperator) ArrayOverlaps(condition Condition, qf *QueryFilter) {
+	elements := condition.Value.(postgresArray)
+	if len(elements) == 0 {
+		qf.And("1 = 0")
+		return
+	}
+	qf.And(condition.Key+" && ?", elements)
+}
+func (m PostgresOperator) ArrayLength(condition Condition, qf *QueryFilter) {
+	qf.And(fmt.Sprintf("cardinality(%s) = ?", condition.Key), condition.Value)
+}
+func (m PostgresOperator) Like(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" LIKE ?", condition.Value)
+}
+func (m PostgresOperator) NotLike(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" NOT LIKE ?", condition.Value)
+}
+
+// Contains 同时用于 StartsWith、EndsWith，值已转义并添加通配符
+func (m PostgresOperator) Contains(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" LIKE ?"+likeClause(Postgres), condition.Value)
+}
+func (m PostgresOperator) ILike(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" ILIKE ?"+likeClause(Postgres), condition.Value)
+}
+
+// IEqual 值已转义且不含通配符，ILIKE 相当于忽略大小写的相等
+func (m PostgresOperator) IEqual(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key+" ILIKE ?"+likeClause(Postgres), condition.Value)
+}
+func (m PostgresOperator) IsNull(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key + " IS NULL")
+}
+func (m PostgresOperator) IsNotNull(condition Condition, qf *QueryFilter) {
+	qf.And(condition.Key + " IS NOT NULL")
+}
+func (m PostgresOperator) Between(condition Condition, qf *QueryFilter) {
+	low, high, _ := betweenValues(condition.Value)
+	qf.And(condition.Key+" BETWEEN ? AND ?", low, high)
+}
+func (m PostgresOperator) NotBetween(condition Condition, qf *QueryFilter) {
+	low, high, _ := betweenValues(condition.Value)
+	qf.And(condition.Key+" NOT BETWEEN ? AND ?", low, high)
+}
+func (m PostgresOperator) Exists(condition Condition, qf *QueryFilter) {
+	qf.And("EXISTS (?)", condition.Value)
+}
+func (m PostgresOperator) NotExists(condition Condition, qf *QueryFilter) {
+	qf.And("NOT EXISTS (?)", condition.Value)
+}
 
 func init() {
 	RegisterDatabase(Postgres, &PostgresDatabase{})
@@ -0,0 +1,94 @@
+package dac
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// operation 语句类型，执行前按类型改写语句
+type operation int
+
+const (
+	opQuery operation = iota
+	opCreate
+	opUpdate
+	opDelete
+)
+
+func (op operation) String() string {
+	switch op {
+	case opCreate:
+		return "create"
+	case opUpdate:
+		return "update"
+	case opDelete:
+		return "delete"
+	}
+	return "query"
+}
+
+// prepare 执行语句前的统一处理，value 为 Find、Create 等方法的参数，返回错误时不执行语句
+func (d *Database) prepare(op operation, value interface{}) error {
+	if err := d.applyTenant(op, value); err != nil {
+		d.err = err
+		return err
+	}
+	if err := d.applySoftDelete(op, value); err != nil {
+		d.err = err
+		return err
+	}
+	if err := d.applyActor(op, value); err != nil {
+		d.err = err
+		return err
+	}
+	return nil
+}
+
+// filtered 是否添加了租户或软删除条件，此时 Save 没有更新到行不能改为 upsert，
+// 否则会覆盖其他租户的行或恢复已删除的行
+func (d *Database) filtered() bool {
+	tenant := d.tenantApplied && d.crossTenant == ""
+	softDelete := d.softDeleteApplied && d.deletedMode != withDeleted && !d.db.Statement.Unscoped
+	return tenant || softDelete
+}
+
+// target 解析语句的模型和表名，表名为 Table 设置的别名或模型的表名，无法解析模型时 schema 为 nil
+func (d *Database) target(value interface{}) (*schema.Schema, string) {
+	stmt := d.db.Statement
+	model := stmt.Model
+	if model == nil {
+		model = value
+	}
+	var s *schema.Schema
+	if model != nil {
+		parser := &gorm.Statement{DB: d.db}
+		if err := parser.Parse(model); err == nil {
+			s = parser.Schema
+		}
+	}
+	table := stmt.Table
+	if table == "" && s != nil {
+		table = s.Table
+	}
+	return s, table
+}
+
+// clone 使用 db 创建新实例，保留版本、租户等设置，不保留语句相关的状态
+func (d *Database) clone(db *gorm.DB) *Database {
+	return &Database{
+		db:          db,
+		DBType:      d.DBType,
+		da:          d.da,
+		dbM:         d.dbM,
+		batchSize:   d.batchSize,
+		version:     d.version,
+		tenant:      d.tenant,
+		crossTenant: d.crossTenant,
+		deletedMode: d.deletedMode,
+		cacheTTL:    d.cacheTTL,
+		cacheTags:   d.cacheTags,
+		pending:     d.pending,
+		metrics:     d.metrics,
+		retry:       d.retry,
+	}
+}
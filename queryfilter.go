@@ -5,6 +5,7 @@ import "fmt"
 type QueryFilter struct {
 	Query string
 	Args  []any
+	Err   error // 无法生成条件时的错误，如数据库不支持的操作符
 }
 
 func (qf *QueryFilter) And(query string, args ...any) *QueryFilter {
@@ -0,0 +1,141 @@
+package dac
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RetryPolicy 可重试错误的重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最多执行次数，包括第一次，小于等于 1 时不重试
+	BaseDelay   time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxDelay    time.Duration // 等待时间上限，为 0 时不限制
+	// Retryable 判断错误是否可以重试，为 nil 时使用 IsRetryable
+	Retryable func(dbType DBType, err error) bool
+}
+
+// DefaultRetryPolicy 默认的重试策略，读语句和 Transaction 默认使用
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   20 * time.Millisecond,
+	MaxDelay:    500 * time.Millisecond,
+}
+
+// IsRetryable 默认的可重试错误：MySQL 死锁、PostgreSQL 死锁和可串行化冲突、
+// ClickHouse 并发查询过多以及连接数过多、连接断开等瞬时错误
+func IsRetryable(dbType DBType, err error) bool {
+	switch ClassifyError(dbType, err) {
+	case ErrorClassDeadlock, ErrorClassSerialization, ErrorClassOverloaded, ErrorClassConnection:
+		return true
+	}
+	return false
+}
+
+// Retry 设置当前实例的重试策略，覆盖 DefaultRetryPolicy，MaxAttempts 为 1 时不重试
+func (d *Database) Retry(policy RetryPolicy) *Database {
+	tx := d.getInstance()
+	tx.retry = &policy
+	return tx
+}
+
+// RetryWrites 标记当前的写语句可以安全地重复执行，Create、Save、Updates、Update、Delete 出错时按重试策略重试
+// 写语句默认不重试，语句已在数据库执行成功而连接断开时，重试会重复写入
+func (d *Database) RetryWrites() *Database {
+	tx := d.getInstance()
+	tx.retryWrites = true
+	return tx
+}
+
+// retryPolicy 当前语句的重试策略，事务中的单条语句不重试，由 Transaction 重新执行整个事务
+func (d *Database) retryPolicy() (RetryPolicy, bool) {
+	policy := DefaultRetryPolicy
+	if d.retry != nil {
+		policy = *d.retry
+	}
+	if policy.MaxAttempts <= 1 || d.inTransaction() {
+		return policy, false
+	}
+	return policy, true
+}
+
+// inTransaction 语句是否在事务中执行
+func (d *Database) inTransaction() bool {
+	_, ok := d.db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// do 执行 fn，返回可重试的错误时等待后重新执行，直到成功、次数用完或 ctx 结束
+func (p RetryPolicy) do(ctx context.Context, dbType DBType, fn func() error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.MaxAttempts || !retryable(dbType, err) {
+			return err
+		}
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff 第 attempt 次失败后的等待时间，在指数退避的一半到全部之间随机取值
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retryQuery 执行读语句，每次重试基于执行前的语句重新构建
+func (d *Database) retryQuery(run func(db *gorm.DB) *gorm.DB) *Database {
+	policy, ok := d.retryPolicy()
+	if !ok || d.db.Error != nil {
+		return d.useSourceDB(run(d.db))
+	}
+	base := d.db.Session(&gorm.Session{})
+	var result *gorm.DB
+	_ = policy.do(d.db.Statement.Context, d.DBType, func() error {
+		result = run(base)
+		return result.Error
+	})
+	return d.useSourceDB(result)
+}
+
+// retryWrite 执行写操作，只有 RetryWrites 标记过的语句才会重试
+func (d *Database) retryWrite(exec func()) {
+	policy, ok := d.retryPolicy()
+	if !ok || !d.retryWrites || d.err != nil || d.db.Error != nil {
+		exec()
+		return
+	}
+	base := d.db.Session(&gorm.Session{})
+	_ = policy.do(d.db.Statement.Context, d.DBType, func() error {
+		d.db, d.err = base, nil
+		exec()
+		if d.err != nil {
+			return d.err
+		}
+		return d.db.Error
+	})
+}
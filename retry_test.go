@@ -0,0 +1,131 @@
+package dac
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		dbType    DBType
+		err       error
+		want      ErrorClass
+		retryable bool
+	}{
+		{Mysql, nil, ErrorClassNone, false},
+		{Mysql, gorm.ErrRecordNotFound, ErrorClassNotFound, false},
+		{Mysql, fmt.Errorf("wrap: %w", ErrStaleObject), ErrorClassStaleObject, false},
+		{Mysql, ErrTenantRequired, ErrorClassTenant, false},
+		{Mysql, ErrUnsupported, ErrorClassUnsupported, false},
+		{Mysql, context.DeadlineExceeded, ErrorClassTimeout, false},
+		{Mysql, context.Canceled, ErrorClassCanceled, false},
+		{Mysql, driver.ErrBadConn, ErrorClassConnection, true},
+		{Mysql, io.ErrUnexpectedEOF, ErrorClassConnection, true},
+		{Postgres, fmt.Errorf("write: %w", syscall.ECONNRESET), ErrorClassConnection, true},
+		{Mysql, errors.New("Error 1062 (23000): Duplicate entry '1' for key 'PRIMARY'"), ErrorClassDuplicateKey, false},
+		{Mysql, errors.New("Error 1213 (40001): Deadlock found when trying to get lock"), ErrorClassDeadlock, true},
+		{Mysql, errors.New("Error 1205 (HY000): Lock wait timeout exceeded"), ErrorClassTimeout, false},
+		{Mysql, errors.New("Error 1040: Too many connections"), ErrorClassOverloaded, true},
+		{Mysql, errors.New("invalid connection"), ErrorClassConnection, true},
+		{Postgres, errors.New("ERROR: deadlock detected (SQLSTATE 40P01)"), ErrorClassDeadlock, true},
+		{Postgres, errors.New("ERROR: could not serialize access (SQLSTATE 40001)"), ErrorClassSerialization, true},
+		{Postgres, errors.New("ERROR: canceling statement due to statement timeout (SQLSTATE 57014)"), ErrorClassTimeout, false},
+		{Postgres, errors.New("FATAL: connection failure (SQLSTATE 08006)"), ErrorClassConnection, true},
+		{Postgres, errors.New("ERROR: duplicate key value (SQLSTATE 23505)"), ErrorClassDuplicateKey, false},
+		{Clickhouse, errors.New("code: 202, message: Too many simultaneous queries"), ErrorClassOverloaded, true},
+		{Clickhouse, errors.New("code: 159, message: Timeout exceeded"), ErrorClassTimeout, false},
+		{Clickhouse, errors.New("code: 394, message: Query was cancelled"), ErrorClassCanceled, false},
+		// 错误码只按对应数据库识别
+		{Clickhouse, errors.New("Error 1213: Deadlock found"), ErrorClassOther, false},
+		{Mysql, errors.New("syntax error"), ErrorClassOther, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%v", tt.dbType, tt.err), func(t *testing.T) {
+			if got := ClassifyError(tt.dbType, tt.err); got != tt.want {
+				t.Errorf("ClassifyError = %q, want %q", got, tt.want)
+			}
+			if got := IsRetryable(tt.dbType, tt.err); got != tt.retryable {
+				t.Errorf("IsRetryable = %v, want %v", got, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	deadlock := errors.New("Error 1213 (40001): Deadlock found when trying to get lock")
+	policy := RetryPolicy{MaxAttempts: 3}
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		run       func(d *Database) error
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name: "read retried", failures: 2, err: deadlock, wantCalls: 3,
+			run: func(d *Database) error { var rows []tenantOrder; return d.Find(&rows).Error() },
+		},
+		{
+			name: "read gives up", failures: 5, err: deadlock, wantCalls: 3, wantErr: true,
+			run: func(d *Database) error { var rows []tenantOrder; return d.Find(&rows).Error() },
+		},
+		{
+			name: "read not retryable", failures: 1, err: errors.New("Error 1064: syntax error"), wantCalls: 1, wantErr: true,
+			run: func(d *Database) error { var rows []tenantOrder; return d.Find(&rows).Error() },
+		},
+		{
+			name: "write not marked", failures: 1, err: deadlock, wantCalls: 1, wantErr: true,
+			run: func(d *Database) error { return d.Model(&tenantOrder{ID: 1}).Update("name", "x").Error() },
+		},
+		{
+			name: "write marked", failures: 1, err: deadlock, wantCalls: 2,
+			run: func(d *Database) error {
+				return d.RetryWrites().Model(&tenantOrder{ID: 1}).Update("name", "x").Error()
+			},
+		},
+		{
+			name: "not retried inside transaction", failures: 1, err: deadlock, wantCalls: 2,
+			run: func(d *Database) error {
+				// 语句本身不重试，由 Transaction 重新执行整个事务
+				return d.Transaction(func(tx *Database) error {
+					var rows []tenantOrder
+					return tx.Find(&rows).Error()
+				})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, b := newFakeDB(t, Mysql)
+			calls := 0
+			fail := func() error {
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			}
+			b.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+				return nil, nil, fail()
+			}
+			b.exec = func(query string, args []driver.NamedValue) (int64, error) {
+				return 1, fail()
+			}
+			err := tt.run(NewDatabase(Mysql).Use(db).Retry(policy))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d: %q", calls, tt.wantCalls, b.statements())
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package dac
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ColumnInfo 数据库中的列信息，Type 使用 datatypes.go 中的 TYPE_* 类型
+type ColumnInfo struct {
+	Name       string
+	Type       string
+	RawType    string // 数据库返回的原始类型
+	Nullable   bool
+	PrimaryKey bool
+}
+
+// IndexInfo 数据库中的索引信息
+type IndexInfo struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Primary bool
+}
+
+// TableSchema 表结构
+type TableSchema struct {
+	Name    string
+	Columns []ColumnInfo
+	Indexes []IndexInfo
+}
+
+// Column 根据列名获取列信息
+func (ts *TableSchema) Column(name string) (ColumnInfo, bool) {
+	for _, c := range ts.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return ColumnInfo{}, false
+}
+
+// Index 根据索引名获取索引信息
+func (ts *TableSchema) Index(name string) (IndexInfo, bool) {
+	for _, idx := range ts.Indexes {
+		if strings.EqualFold(idx.Name, name) {
+			return idx, true
+		}
+	}
+	return IndexInfo{}, false
+}
+
+type columnRow struct {
+	Name       string `gorm:"column:name"`
+	RawType    string `gorm:"column:raw_type"`
+	Nullable   bool   `gorm:"column:nullable"`
+	PrimaryKey bool   `gorm:"column:primary_key"`
+}
+
+type indexRow struct {
+	IndexName  string `gorm:"column:index_name"`
+	ColumnName string `gorm:"column:column_name"`
+	IsUnique   bool   `gorm:"column:is_unique"`
+	IsPrimary  bool   `gorm:"column:is_primary"`
+}
+
+const (
+	mysqlColumnsSQL = `SELECT COLUMN_NAME AS name, COLUMN_TYPE AS raw_type, IS_NULLABLE = 'YES' AS nullable, COLUMN_KEY = 'PRI' AS primary_key
+FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`
+	mysqlIndexesSQL = `SELECT INDEX_NAME AS index_name, COLUMN_NAME AS column_name, NON_UNIQUE = 0 AS is_unique, INDEX_NAME = 'PRIMARY' AS is_primary
+FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY INDEX_NAME, SEQ_IN_INDEX`
+	postgresColumnsSQL = `SELECT a.attname AS name, format_type(a.atttypid, a.atttypmod) AS raw_type, NOT a.attnotnull AS nullable,
+EXISTS (SELECT 1 FROM pg_catalog.pg_index i WHERE i.indrelid = c.oid AND i.indisprimary AND a.attnum = ANY(i.indkey)) AS primary_key
+FROM pg_catalog.pg_attribute a
+JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relname = ? AND n.nspname = current_schema() AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum`
+	postgresIndexesSQL = `SELECT ic.relname AS index_name, a.attname AS column_name, i.indisunique AS is_unique, i.indisprimary AS is_primary
+FROM pg_catalog.pg_index i
+JOIN pg_catalog.pg_class c ON c.oid = i.indrelid
+JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.attnum
+WHERE c.relname = ? AND n.nspname = current_schema() ORDER BY ic.relname, k.ord`
+	clickhouseColumnsSQL = `SELECT name, type AS raw_type, startsWith(type, 'Nullable(') AS nullable, is_in_primary_key = 1 AS primary_key
+FROM system.columns WHERE database = currentDatabase() AND table = ? ORDER BY position`
+	clickhouseIndexesSQL = `SELECT name AS index_name, expr AS column_name, 0 AS is_unique, 0 AS is_primary
+FROM system.data_skipping_indices WHERE database = currentDatabase() AND table = ? ORDER BY name`
+	clickhousePrimaryKeySQL = `SELECT primary_key FROM system.tables WHERE database = currentDatabase() AND name = ?`
+)
+
+// InspectTable 读取数据库中表的实际结构
+// MySQL 读取 information_schema，PostgreSQL 读取 pg_catalog，ClickHouse 读取 system.columns
+func (d *Database) InspectTable(name string) (*TableSchema, error) {
+	tx := d.getInstance()
+	db := tx.db.Session(&gorm.Session{NewDB: true})
+	var columnsSQL, indexesSQL string
+	switch tx.DBType {
+	case Mysql:
+		columnsSQL, indexesSQL = mysqlColumnsSQL, mysqlIndexesSQL
+	case Postgres:
+		columnsSQL, indexesSQL = postgresColumnsSQL, postgresIndexesSQL
+	case Clickhouse:
+		columnsSQL, indexesSQL = clickhouseColumnsSQL, clickhouseIndexesSQL
+	default:
+		return nil, fmt.Errorf("schema introspection is not supported on %s", tx.DBType)
+	}
+
+	var columns []columnRow
+	if err := db.Raw(columnsSQL, name).Scan(&columns).Error; err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s does not exist", name)
+	}
+	ts := &TableSchema{Name: name}
+	for _, c := range columns {
+		ts.Columns = append(ts.Columns, ColumnInfo{
+			Name:       c.Name,
+			Type:       NormalizeColumnType(tx.DBType, c.RawType),
+			RawType:    c.RawType,
+			Nullable:   c.Nullable || (tx.DBType == Clickhouse && clickhouseNullable(c.RawType)),
+			PrimaryKey: c.PrimaryKey,
+		})
+	}
+
+	var indexes []indexRow
+	if err := db.Raw(indexesSQL, name).Scan(&indexes).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range indexes {
+		if n := len(ts.Indexes); n > 0 && ts.Indexes[n-1].Name == row.IndexName {
+			ts.Indexes[n-1].Columns = append(ts.Indexes[n-1].Columns, row.ColumnName)
+			continue
+		}
+		ts.Indexes = append(ts.Indexes, IndexInfo{
+			Name:    row.IndexName,
+			Columns: []string{row.ColumnName},
+			Unique:  row.IsUnique,
+			Primary: row.IsPrimary,
+		})
+	}
+
+	// ClickHouse 的主键不在索引表中，单独读取排序键
+	if tx.DBType == Clickhouse {
+		var primaryKey string
+		if err := db.Raw(clickhousePrimaryKeySQL, name).Scan(&primaryKey).Error; err != nil {
+			return nil, err
+		}
+		if primaryKey != "" {
+			idx := IndexInfo{Name: "PRIMARY", Primary: true, Unique: false}
+			for _, c := range strings.Split(primaryKey, ",") {
+				idx.Columns = append(idx.Columns, strings.TrimSpace(c))
+			}
+			ts.Indexes = append(ts.Indexes, idx)
+		}
+	}
+	return ts, nil
+}
+
+var (
+	typeWrapperRe = regexp.MustCompile(`^(?:nullable|lowcardinality)\((.*)\)$`)
+	typeArgsRe    = regexp.MustCompile(`\([^()]*\)`)
+)
+
+// clickhouseNullable ClickHouse 的类型是否可为空，Nullable 可能包在 LowCardinality 中，如 LowCardinality(Nullable(String))
+func clickhouseNullable(rawType string) bool {
+	t := strings.ToLower(strings.TrimSpace(rawType))
+	if strings.HasPrefix(t, "lowcardinality(") && strings.HasSuffix(t, ")") {
+		t = strings.TrimSpace(t[len("lowcardinality(") : len(t)-1])
+	}
+	return strings.HasPrefix(t, "nullable(")
+}
+
+// NormalizeColumnType 将数据库返回的原始类型转换为 TYPE_* 类型，无法识别时返回去掉参数后的小写类型
+func NormalizeColumnType(dbType DBType, rawType string) string {
+	t := strings.ToLower(strings.TrimSpace(rawType))
+	for {
+		match := typeWrapperRe.FindStringSubmatch(t)
+		if match == nil {
+			break
+		}
+		t = match[1]
+	}
+	// MySQL 中 tinyint(1) 用作布尔类型
+	if dbType == Mysql && strings.HasPrefix(t, "tinyint(1)") {
+		return TYPE_BOOLEAN
+	}
+	// 逐层去掉参数，如 timestamp(6) without time zone、array(nullable(string))，再合并多余的空格
+	for typeArgsRe.MatchString(t) {
+		t = typeArgsRe.ReplaceAllString(t, "")
+	}
+	t = strings.Join(strings.Fields(t), " ")
+	t = strings.TrimSuffix(t, " unsigned")
+
+	switch t {
+	case "int", "integer", "mediumint", "int4", "int32", "uint32", "serial":
+		return TYPE_INT
+	case "bigint", "int8", "int64", "uint64", "bigserial":
+		if dbType == Clickhouse && t == "int8" {
+			return TYPE_TINYINT
+		}
+		return TYPE_BIGINT
+	case "smallint", "int2", "int16", "uint16", "smallserial":
+		return TYPE_SMALLINT
+	case "tinyint", "uint8":
+		return TYPE_TINYINT
+	case "decimal", "decimal32", "decimal64", "decimal128", "decimal256":
+		return TYPE_DECIMAL
+	case "numeric":
+		return TYPE_NUMERIC
+	case "real", "float", "float4", "float32":
+		return TYPE_REAL
+	case "double", "double precision", "float8", "float64":
+		return TYPE_DOUBLE
+	case "bool", "boolean":
+		return TYPE_BOOLEAN
+	case "char", "character", "fixedstring", "bpchar":
+		return TYPE_CHAR
+	case "varchar", "character varying", "string":
+		return TYPE_VARCHAR
+	case "text", "tinytext", "mediumtext":
+		return TYPE_TEXT
+	case "longtext":
+		return TYPE_LONG_TEXT
+	case "enum", "enum8", "enum16":
+		return TYPE_ENUM
+	case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary":
+		return TYPE_BLOB
+	case "bytea":
+		return TYPE_BYTEA
+	case "uuid":
+		return TYPE_UUID
+	case "date", "date32":
+		return TYPE_DATE
+	case "time", "time without time zone", "time with time zone":
+		return TYPE_TIME
+	case "datetime", "datetime64", "timestamp", "timestamp without time zone", "timestamp with time zone":
+		return TYPE_TIMESTAMP
+	case "interval":
+		return TYPE_INTERVAL
+	}
+	return t
+}
+
+// typeFamily 类型分组，同组类型视为兼容
+func typeFamily(t string) string {
+	switch t {
+	case TYPE_INT, TYPE_BIGINT, TYPE_SMALLINT, TYPE_TINYINT:
+		return "integer"
+	case TYPE_REAL, TYPE_DOUBLE:
+		return "float"
+	case TYPE_DECIMAL, TYPE_NUMERIC:
+		return "decimal"
+	case TYPE_CHAR, TYPE_VARCHAR, TYPE_TEXT, TYPE_LONG_TEXT, TYPE_ENUM, TYPE_UUID:
+		return "string"
+	case TYPE_DATE, TYPE_TIME, TYPE_TIMESTAMP:
+		return "time"
+	case TYPE_BLOB, TYPE_BYTEA:
+		return "binary"
+	}
+	return t
+}
+
+func typesCompatible(expected, actual string) bool {
+	if expected == actual || typeFamily(expected) == typeFamily(actual) {
+		return true
+	}
+	// 布尔类型在 MySQL 和 ClickHouse 中可能以 tinyint/UInt8 存储
+	return expected == TYPE_BOOLEAN && actual == TYPE_TINYINT
+}
+
+// TypeMismatch 列类型不一致
+type TypeMismatch struct {
+	Column   string
+	Expected string
+	Actual   string
+}
+
+// IndexMismatch 同名索引的列不一致
+type IndexMismatch struct {
+	Name     string
+	Expected []string
+	Actual   []string
+}
+
+// SchemaDiff 模型与数据库表结构的差异
+type SchemaDiff struct {
+	Table           string
+	MissingColumns  []string // 模型中存在、数据库中不存在
+	ExtraColumns    []string // 数据库中存在、模型中不存在
+	TypeMismatches  []TypeMismatch
+	MissingIndexes  []string
+	ExtraIndexes    []string
+	IndexMismatches []IndexMismatch
+}
+
+// Empty 是否没有差异
+func (sd *SchemaDiff) Empty() bool {
+	return len(sd.MissingColumns) == 0 && len(sd.ExtraColumns) == 0 && len(sd.TypeMismatches) == 0 &&
+		len(sd.MissingIndexes) == 0 && len(sd.ExtraIndexes) == 0 && len(sd.IndexMismatches) == 0
+}
+
+func (sd *SchemaDiff) String() string {
+	if sd.Empty() {
+		return fmt.Sprintf("table %s: no differences", sd.Table)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "table %s:", sd.Table)
+	for _, c := range sd.MissingColumns {
+		fmt.Fprintf(&b, "\n  missing column %s", c)
+	}
+	for _, c := range sd.ExtraColumns {
+		fmt.Fprintf(&b, "\n  extra column %s", c)
+	}
+	for _, m := range sd.TypeMismatches {
+		fmt.Fprintf(&b, "\n  column %s: expected %s, got %s", m.Column, m.Expected, m.Actual)
+	}
+	for _, i := range sd.MissingIndexes {
+		fmt.Fprintf(&b, "\n  missing index %s", i)
+	}
+	for _, i := range sd.ExtraIndexes {
+		fmt.Fprintf(&b, "\n  extra index %s", i)
+	}
+	for _, m := range sd.IndexMismatches {
+		fmt.Fprintf(&b, "\n  index %s: expected (%s), got (%s)", m.Name, strings.Join(m.Expected, ","), strings.Join(m.Actual, ","))
+	}
+	return b.String()
+}
+
+// DiffModel 读取模型对应表的结构并与模型比较
+func (d *Database) DiffModel(model interface{}) (*SchemaDiff, error) {
+	tx := d.getInstance()
+	s, err := schema.Parse(model, &sync.Map{}, tx.db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	live, err := tx.InspectTable(s.Table)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSchema(tx.DBType, s, live), nil
+}
+
+// DiffSchema 比较 gorm 解析的模型结构与数据库中的表结构
+func DiffSchema(dbType DBType, model *schema.Schema, live *TableSchema) *SchemaDiff {
+	diff := &SchemaDiff{Table: live.Name}
+	modelColumns := map[string]bool{}
+	for _, field := range model.Fields {
+		if field.DBName == "" || field.IgnoreMigration {
+			continue
+		}
+		modelColumns[strings.ToLower(field.DBName)] = true
+		col, ok := live.Column(field.DBName)
+		if !ok {
+			diff.MissingColumns = append(diff.MissingColumns, field.DBName)
+			continue
+		}
+		expected := expectedColumnType(dbType, field)
+		if expected != "" && !typesCompatible(expected, col.Type) {
+			diff.TypeMismatches = append(diff.TypeMismatches, TypeMismatch{Column: field.DBName, Expected: expected, Actual: col.Type})
+		}
+	}
+	for _, col := range live.Columns {
+		if !modelColumns[strings.ToLower(col.Name)] {
+			diff.ExtraColumns = append(diff.ExtraColumns, col.Name)
+		}
+	}
+
+	modelIndexes := model.ParseIndexes()
+	names := make([]string, 0, len(modelIndexes))
+	for name := range modelIndexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		idx := modelIndexes[name]
+		expected := make([]string, 0, len(idx.Fields))
+		for _, f := range idx.Fields {
+			if f.Field != nil {
+				expected = append(expected, f.DBName)
+			} else {
+				expected = append(expected, f.Expression)
+			}
+		}
+		actual, ok := live.Index(name)
+		if !ok {
+			diff.MissingIndexes = append(diff.MissingIndexes, name)
+			continue
+		}
+		if !strings.EqualFold(strings.Join(expected, ","), strings.Join(actual.Columns, ",")) {
+			diff.IndexMismatches = append(diff.IndexMismatches, IndexMismatch{Name: name, Expected: expected, Actual: actual.Columns})
+		}
+	}
+	for _, idx := range live.Indexes {
+		if idx.Primary {
+			continue
+		}
+		if _, ok := modelIndexes[idx.Name]; !ok {
+			diff.ExtraIndexes = append(diff.ExtraIndexes, idx.Name)
+		}
+	}
+	return diff
+}
+
+// expectedColumnType 根据 gorm 标签或 Go 类型推断模型字段的 TYPE_* 类型
+func expectedColumnType(dbType DBType, field *schema.Field) string {
+	if t, ok := field.TagSettings["TYPE"]; ok && t != "" {
+		return NormalizeColumnType(dbType, t)
+	}
+	switch field.DataType {
+	case schema.Bool:
+		return TYPE_BOOLEAN
+	case schema.Int, schema.Uint:
+		switch {
+		case field.Size <= 8:
+			return TYPE_TINYINT
+		case field.Size <= 16:
+			return TYPE_SMALLINT
+		case field.Size <= 32:
+			return TYPE_INT
+		}
+		return TYPE_BIGINT
+	case schema.Float:
+		if field.Size <= 32 {
+			return TYPE_REAL
+		}
+		return TYPE_DOUBLE
+	case schema.String:
+		return TYPE_VARCHAR
+	case schema.Time:
+		return TYPE_TIMESTAMP
+	case schema.Bytes:
+		return TYPE_BLOB
+	case "":
+		return ""
+	}
+	return NormalizeColumnType(dbType, string(field.DataType))
+}
@@ -0,0 +1,74 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeColumnType(t *testing.T) {
+	tests := []struct {
+		dbType DBType
+		raw    string
+		want   string
+	}{
+		{Postgres, "timestamp(6) without time zone", TYPE_TIMESTAMP},
+		{Postgres, "time(3) with time zone", TYPE_TIME},
+		{Postgres, "character varying(255)", TYPE_VARCHAR},
+		{Postgres, "numeric(10,2)", TYPE_NUMERIC},
+		{Mysql, "varchar(255)", TYPE_VARCHAR},
+		{Mysql, "tinyint(1)", TYPE_BOOLEAN},
+		{Mysql, "int(11) unsigned", TYPE_INT},
+		{Clickhouse, "Nullable(Decimal(10, 2))", TYPE_DECIMAL},
+		{Clickhouse, "LowCardinality(Nullable(String))", TYPE_VARCHAR},
+		{Clickhouse, "DateTime64(3, 'Asia/Shanghai')", TYPE_TIMESTAMP},
+		{Clickhouse, "Array(Nullable(String))", "array"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeColumnType(tt.dbType, tt.raw); got != tt.want {
+			t.Errorf("NormalizeColumnType(%s, %q) = %q, want %q", tt.dbType, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestClickhouseNullable(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want bool
+	}{
+		{"Nullable(String)", true},
+		{"LowCardinality(Nullable(String))", true},
+		{"LowCardinality(String)", false},
+		{"String", false},
+		{"Array(Nullable(String))", false},
+	}
+	for _, tt := range tests {
+		if got := clickhouseNullable(tt.raw); got != tt.want {
+			t.Errorf("clickhouseNullable(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestInspectTableClickhouseNullable(t *testing.T) {
+	db, b := newFakeDB(t, Clickhouse)
+	b.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.Contains(query, "system.columns") {
+			return []string{"name", "raw_type", "nullable", "primary_key"}, [][]driver.Value{
+				{"id", "UInt64", false, true},
+				{"region", "LowCardinality(Nullable(String))", false, false},
+				{"name", "LowCardinality(String)", false, false},
+			}, nil
+		}
+		return nil, nil, nil
+	}
+	ts, err := NewDatabase(Clickhouse).Use(db).InspectTable("events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"id": false, "region": true, "name": false}
+	for _, c := range ts.Columns {
+		if c.Nullable != want[c.Name] {
+			t.Errorf("column %s nullable = %v, want %v", c.Name, c.Nullable, want[c.Name])
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SlowQueryConfig 慢查询日志的配置，通过 RegisterSlowQueryLog 按数据库类型设置
+type SlowQueryConfig struct {
+	Threshold time.Duration // 超过该时长的语句记录为慢查询，小于等于 0 时只使用 Tables 中的阈值
+	// Tables 按表名覆盖 Threshold，小于等于 0 时该表不记录
+	// 表名与缓存标签相同：Table("orders AS o")、FINAL 取原表名 orders；设置了模型时取模型的表名，
+	// 因此 Model 配合 Table 指定的分表、以及 Union 等子查询匹配不到这里的表名，使用 Threshold
+	Tables map[string]time.Duration
+
+	// Explain 对慢查询的 SELECT 执行 EXPLAIN 并附加执行计划，ClickHouse 使用 EXPLAIN indexes = 1
+	// EXPLAIN 在返回前同步执行，使用调用方的连接和事务，会再增加一条可能同样慢的语句；
+	// PostgreSQL 事务中语句失败后事务已中止，此时 EXPLAIN 必然失败，错误记录在 SlowQuery.ExplainErr
+	Explain bool
+	// ExplainSampleRate EXPLAIN 的采样率，取值 0 到 1，为 0 时视为 1，用于限制 EXPLAIN 带来的额外查询
+	ExplainSampleRate float64
+
+	// Redact 渲染日志中的参数，为 nil 时只输出参数类型，不输出值
+	Redact func(arg interface{}) string
+	// Hook 接收慢查询，为 nil 时通过 gorm 的日志输出警告
+	Hook func(ctx context.Context, q SlowQuery)
+}
+
+// SlowQuery 一条慢查询
+type SlowQuery struct {
+	DBType     DBType
+	Operation  string
+	Table      string
+	SQL        string   // 带占位符的 SQL
+	Args       []string // 经过 Redact 处理的参数
+	Caller     string   // 调用方的 file:line
+	Duration   time.Duration
+	Threshold  time.Duration
+	Plan       string // EXPLAIN 的结果，未执行时为空
+	ExplainErr error
+	Err        error
+}
+
+var (
+	slowQueryMu      sync.RWMutex
+	slowQueryConfigs = map[DBType]*SlowQueryConfig{}
+)
+
+// RegisterSlowQueryLog 设置数据库类型的慢查询日志，cfg 为 nil 时关闭
+func RegisterSlowQueryLog(dbType DBType, cfg *SlowQueryConfig) {
+	slowQueryMu.Lock()
+	defer slowQueryMu.Unlock()
+	if cfg == nil {
+		delete(slowQueryConfigs, dbType)
+		return
+	}
+	c := *cfg
+	slowQueryConfigs[dbType] = &c
+}
+
+func getSlowQueryConfig(dbType DBType) *SlowQueryConfig {
+	slowQueryMu.RLock()
+	defer slowQueryMu.RUnlock()
+	return slowQueryConfigs[dbType]
+}
+
+// threshold 表的慢查询阈值，返回 0 时不记录
+func (c *SlowQueryConfig) threshold(table string) time.Duration {
+	if t, ok := c.Tables[table]; ok {
+		if t <= 0 {
+			return 0
+		}
+		return t
+	}
+	if c.Threshold <= 0 {
+		return 0
+	}
+	return c.Threshold
+}
+
+func (c *SlowQueryConfig) sampled() bool {
+	rate := c.ExplainSampleRate
+	return rate <= 0 || rate >= 1 || rand.Float64() < rate
+}
+
+// logSlowQuery 语句超过阈值时记录慢查询
+func (d *Database) logSlowQuery(cfg *SlowQueryConfig, name, table string, duration time.Duration, db *gorm.DB, err error) {
+	threshold := cfg.threshold(table)
+	if threshold <= 0 || duration < threshold {
+		return
+	}
+	stmt := db.Statement
+	sql, vars := lastSQL(db)
+	q := SlowQuery{
+		DBType:    d.DBType,
+		Operation: name,
+		Table:     table,
+		SQL:       sql,
+		Caller:    caller(),
+		Duration:  duration,
+		Threshold: threshold,
+		Err:       err,
+	}
+	for _, v := range vars {
+		q.Args = append(q.Args, redactArg(cfg, v))
+	}
+	if cfg.Explain && q.SQL != "" && isSelect(q.SQL) && cfg.sampled() {
+		q.Plan, q.ExplainErr = explain(d.DBType, db, q.SQL, vars)
+	}
+	if cfg.Hook != nil {
+		cfg.Hook(stmt.Context, q)
+		return
+	}
+	msg := fmt.Sprintf("slow query %s on %s took %s (threshold %s) at %s: %s %v", q.Operation, q.Table, q.Duration, q.Threshold, q.Caller, q.SQL, q.Args)
+	if q.Plan != "" {
+		msg += "\n" + q.Plan
+	}
+	db.Logger.Warn(stmt.Context, "%s", msg)
+}
+
+func redactArg(cfg *SlowQueryConfig, arg interface{}) string {
+	if cfg.Redact != nil {
+		return cfg.Redact(arg)
+	}
+	if arg == nil {
+		return "<nil>"
+	}
+	rv := reflect.ValueOf(arg)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("<%T len=%d>", arg, rv.Len())
+	}
+	return fmt.Sprintf("<%T>", arg)
+}
+
+func isSelect(sql string) bool {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH", "(SELECT":
+		return true
+	}
+	return false
+}
+
+// explain 执行 EXPLAIN，每行的各列用制表符连接
+func explain(dbType DBType, db *gorm.DB, query string, vars []interface{}) (string, error) {
+	prefix := "EXPLAIN "
+	if dbType == Clickhouse {
+		prefix = "EXPLAIN indexes = 1 "
+	}
+	rows, err := db.Session(&gorm.Session{NewDB: true}).Raw(prefix+query, vars...).Rows()
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	if len(columns) > 1 {
+		lines = append(lines, strings.Join(columns, "\t"))
+	}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = v.String
+		}
+		lines = append(lines, strings.Join(cells, "\t"))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+// caller 本包和 gorm 之外的第一个调用方
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	pkg := reflect.TypeOf(Database{}).PkgPath() + "."
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, pkg) && !strings.HasPrefix(frame.Function, "gorm.io/") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package dac
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(d *Database) error
+		sql  string
+		args []string
+		plan string
+	}{
+		{
+			name: "find",
+			run: func(d *Database) error {
+				var rows []metricRow
+				return d.Query("name = ?", "a").Find(&rows).Error()
+			},
+			sql:  "SELECT * FROM `metric_rows` WHERE name = ?",
+			args: []string{"<string len=1>"},
+			plan: "id\tselect_type\n1\tSIMPLE",
+		},
+		{
+			name: "update",
+			run: func(d *Database) error {
+				return d.Model(&metricRow{ID: 1}).Update("name", "x").Error()
+			},
+			sql:  "UPDATE `metric_rows` SET `name`=? WHERE `id` = ?",
+			args: []string{"<string len=1>", "<uint>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logged []SlowQuery
+			RegisterSlowQueryLog(Mysql, &SlowQueryConfig{
+				Threshold: time.Nanosecond,
+				Explain:   true,
+				Hook: func(ctx context.Context, q SlowQuery) {
+					logged = append(logged, q)
+				},
+			})
+			defer RegisterSlowQueryLog(Mysql, nil)
+			db, b := newFakeDB(t, Mysql)
+			var explained []driver.NamedValue
+			b.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+				if strings.HasPrefix(query, "EXPLAIN ") {
+					explained = args
+					return []string{"id", "select_type"}, [][]driver.Value{{int64(1), "SIMPLE"}}, nil
+				}
+				return []string{"id", "name"}, nil, nil
+			}
+			if err := tt.run(NewDatabase(Mysql).Use(db)); err != nil {
+				t.Fatal(err)
+			}
+			if len(logged) != 1 {
+				t.Fatalf("logged %+v, want 1 slow query", logged)
+			}
+			q := logged[0]
+			if q.SQL != tt.sql || strings.Join(q.Args, ",") != strings.Join(tt.args, ",") || q.Plan != tt.plan {
+				t.Errorf("slow query %+v, want SQL %q, args %v, plan %q", q, tt.sql, tt.args, tt.plan)
+			}
+			if q.Table != "metric_rows" || q.ExplainErr != nil {
+				t.Errorf("table = %q, explain err = %v", q.Table, q.ExplainErr)
+			}
+			if tt.plan != "" && (len(explained) != 1 || explained[0].Value != "a") {
+				t.Errorf("EXPLAIN args %v, want [a]", explained)
+			}
+			if tt.plan == "" && b.count("EXPLAIN") != 0 {
+				t.Errorf("statements %q, want no EXPLAIN", b.statements())
+			}
+		})
+	}
+}
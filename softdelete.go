@@ -0,0 +1,240 @@
+package dac
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// SoftDeleteStrategy 软删除策略
+type SoftDeleteStrategy int
+
+const (
+	SoftDeleteNone      SoftDeleteStrategy = iota
+	SoftDeleteTimestamp                    // 删除时设置 deleted_at 为当前时间，查询过滤 deleted_at IS NULL
+	SoftDeleteFlag                         // 删除时设置 is_deleted 为 true，查询过滤 is_deleted = false
+	// SoftDeleteVersionRow 仅 ClickHouse，表引擎为 ReplacingMergeTree(version)
+	// 删除时插入一行版本号更大、标记列为 1 的副本，不产生 mutation；查询使用 FINAL 并过滤标记列为 0
+	// 引擎不要设置 is_deleted 参数，否则 FINAL 会直接去掉已删除的行，OnlyDeleted 查询不到数据
+	SoftDeleteVersionRow
+)
+
+// SoftDelete 表的软删除配置
+type SoftDelete struct {
+	Strategy      SoftDeleteStrategy
+	Column        string // 删除时间或删除标记列，默认 deleted_at 或 is_deleted
+	VersionColumn string // SoftDeleteVersionRow 的版本列，默认 version
+}
+
+// SoftDeleteTableInfo 按数据库类型设置表的软删除策略，例如 MySQL 使用 SoftDeleteTimestamp，ClickHouse 使用 SoftDeleteVersionRow
+// 未实现该接口且模型包含 gorm.DeletedAt 字段时，由 gorm 处理软删除
+type SoftDeleteTableInfo interface {
+	TableInfo
+	SoftDelete(dbType DBType) SoftDelete
+}
+
+// deletedMode 查询已删除行的方式
+type deletedMode int
+
+const (
+	excludeDeleted deletedMode = iota
+	withDeleted
+	onlyDeleted
+)
+
+// WithDeleted 查询包含已软删除的行
+func (d *Database) WithDeleted() *Database {
+	tx := d.getInstance()
+	tx.deletedMode = withDeleted
+	return tx
+}
+
+// OnlyDeleted 只查询已软删除的行
+func (d *Database) OnlyDeleted() *Database {
+	tx := d.getInstance()
+	tx.deletedMode = onlyDeleted
+	return tx
+}
+
+// softDeleteOf 获取表的软删除配置，gorm 为模型包含 gorm.DeletedAt 字段时由 gorm 处理
+func (d *Database) softDeleteOf(s *schema.Schema) (sd SoftDelete, gormManaged bool, err error) {
+	if s == nil {
+		return sd, false, nil
+	}
+	if ti, ok := reflect.New(s.ModelType).Interface().(SoftDeleteTableInfo); ok {
+		sd = ti.SoftDelete(d.DBType)
+		switch sd.Strategy {
+		case SoftDeleteTimestamp:
+			if sd.Column == "" {
+				sd.Column = "deleted_at"
+			}
+		case SoftDeleteFlag:
+			if sd.Column == "" {
+				sd.Column = "is_deleted"
+			}
+		case SoftDeleteVersionRow:
+			if d.DBType != Clickhouse {
+				return sd, false, fmt.Errorf("%w: version row soft delete on %s", ErrUnsupported, d.DBType)
+			}
+			if sd.Column == "" {
+				sd.Column = "is_deleted"
+			}
+			if sd.VersionColumn == "" {
+				sd.VersionColumn = "version"
+			}
+		}
+		return sd, false, nil
+	}
+	for _, field := range s.Fields {
+		if field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			return SoftDelete{Strategy: SoftDeleteTimestamp, Column: field.DBName}, true, nil
+		}
+	}
+	return sd, false, nil
+}
+
+// applySoftDelete 查询、更新时按删除方式过滤已软删除的行
+func (d *Database) applySoftDelete(op operation, value interface{}) error {
+	if d.softDeleteApplied || d.derived || (op != opQuery && op != opUpdate) {
+		return nil
+	}
+	s, table := d.target(value)
+	sd, gormManaged, err := d.softDeleteOf(s)
+	if err != nil || sd.Strategy == SoftDeleteNone {
+		return err
+	}
+	d.softDeleteApplied = true
+	column := quoteIdentifier(d.DBType, table+"."+sd.Column)
+	if gormManaged {
+		switch d.deletedMode {
+		case withDeleted:
+			d.db = d.db.Unscoped()
+		case onlyDeleted:
+			d.db = d.db.Unscoped().Where(column + " IS NOT NULL")
+		}
+		return nil
+	}
+	if d.db.Statement.Unscoped {
+		return nil
+	}
+	if sd.Strategy == SoftDeleteVersionRow && op == opQuery {
+		d.useFinal(table)
+	}
+	switch d.deletedMode {
+	case withDeleted:
+	case onlyDeleted:
+		switch sd.Strategy {
+		case SoftDeleteTimestamp:
+			d.db = d.db.Where(column + " IS NOT NULL")
+		case SoftDeleteFlag:
+			d.db = d.db.Where(column+" = ?", true)
+		case SoftDeleteVersionRow:
+			d.db = d.db.Where(column+" = ?", 1)
+		}
+	default:
+		switch sd.Strategy {
+		case SoftDeleteTimestamp:
+			d.db = d.db.Where(column + " IS NULL")
+		case SoftDeleteFlag:
+			d.db = d.db.Where(column+" = ?", false)
+		case SoftDeleteVersionRow:
+			d.db = d.db.Where(column+" = ?", 0)
+		}
+	}
+	return nil
+}
+
+// useFinal 在 FROM 的表后添加 FINAL，读取 ReplacingMergeTree 合并后的最新版本
+func (d *Database) useFinal(table string) {
+	expr := d.db.Statement.TableExpr
+	if expr == nil {
+		expr = &clause.Expr{SQL: quoteIdentifier(d.DBType, table)}
+	}
+	if strings.HasSuffix(strings.ToUpper(strings.TrimSpace(expr.SQL)), " FINAL") {
+		return
+	}
+	// gorm 无法从 "t AS a FINAL" 中解析别名，需要保留原来的表名或别名
+	d.db = d.db.Table(expr.SQL+" FINAL", expr.Vars...)
+	d.db.Statement.Table = table
+}
+
+// softDelete 按表的策略软删除，返回 false 时使用 gorm 的 Delete
+func (d *Database) softDelete(value interface{}) (bool, error) {
+	if d.db.Statement.Unscoped {
+		return false, nil
+	}
+	s, table := d.target(value)
+	sd, gormManaged, err := d.softDeleteOf(s)
+	if err != nil {
+		return true, err
+	}
+	if gormManaged || sd.Strategy == SoftDeleteNone {
+		return false, nil
+	}
+	switch sd.Strategy {
+	case SoftDeleteTimestamp:
+		d.db = d.db.Model(value).Update(sd.Column, time.Now())
+	case SoftDeleteFlag:
+		d.db = d.db.Model(value).Update(sd.Column, true)
+	case SoftDeleteVersionRow:
+		rows, err := versionRows(d, s, sd, table, value)
+		if err != nil {
+			return true, err
+		}
+		if reflect.ValueOf(rows).Len() == 0 {
+			return true, nil
+		}
+		d.db = d.db.Session(&gorm.Session{NewDB: true}).Table(s.Table).Create(rows)
+	}
+	return true, d.db.Error
+}
+
+// versionRows 按主键读取要删除的行的最新版本，设置更大的版本号和删除标记后作为新版本插入
+// 读取时保留语句上的租户等条件，只传入主键的结构体也会写入完整的行；已删除或不存在的行会被跳过
+func versionRows(d *Database, s *schema.Schema, sd SoftDelete, table string, value interface{}) (interface{}, error) {
+	marker, version := s.LookUpField(sd.Column), s.LookUpField(sd.VersionColumn)
+	if marker == nil || version == nil {
+		return nil, fmt.Errorf("model %s requires columns %s and %s for version row soft delete", s.Name, sd.Column, sd.VersionColumn)
+	}
+	ctx := d.db.Statement.Context
+	conds := primaryKeyConditions(ctx, s, value)
+	if len(conds) == 0 {
+		return nil, fmt.Errorf("version row soft delete requires the primary keys of the rows to delete, got %T", value)
+	}
+	keys := d.db.Session(&gorm.Session{NewDB: true})
+	for i, cond := range conds {
+		if i == 0 {
+			keys = keys.Where(cond)
+		} else {
+			keys = keys.Or(cond)
+		}
+	}
+	loader := d.clone(d.db.Session(&gorm.Session{}))
+	loader.useFinal(table)
+	rows := reflect.New(reflect.SliceOf(s.ModelType))
+	column := quoteIdentifier(d.DBType, table+"."+sd.Column)
+	if err := loader.db.Where(keys).Where(column+" = ?", 0).Find(rows.Interface()).Error; err != nil {
+		return nil, err
+	}
+	rv := rows.Elem()
+	now := time.Now()
+	for i := 0; i < rv.Len(); i++ {
+		row := rv.Index(i)
+		var v interface{} = now.UnixNano()
+		if version.FieldType == reflect.TypeOf(time.Time{}) {
+			v = now
+		}
+		if err := version.Set(ctx, row, v); err != nil {
+			return nil, err
+		}
+		if err := marker.Set(ctx, row, 1); err != nil {
+			return nil, err
+		}
+	}
+	return rv.Interface(), nil
+}
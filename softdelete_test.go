@@ -0,0 +1,167 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sdTimestampRow struct {
+	ID        uint
+	Name      string
+	DeletedAt *time.Time
+}
+
+func (sdTimestampRow) TableName() string  { return "sd_rows" }
+func (sdTimestampRow) TableAlias() string { return "" }
+func (sdTimestampRow) SoftDelete(dbType DBType) SoftDelete {
+	return SoftDelete{Strategy: SoftDeleteTimestamp}
+}
+
+type sdFlagRow struct {
+	ID        uint
+	Name      string
+	IsDeleted bool
+}
+
+func (sdFlagRow) TableName() string  { return "sd_flags" }
+func (sdFlagRow) TableAlias() string { return "" }
+func (sdFlagRow) SoftDelete(dbType DBType) SoftDelete {
+	return SoftDelete{Strategy: SoftDeleteFlag}
+}
+
+type sdEvent struct {
+	ID        uint
+	Name      string
+	IsDeleted uint8
+	Version   int64
+}
+
+func (sdEvent) TableName() string  { return "sd_events" }
+func (sdEvent) TableAlias() string { return "" }
+func (sdEvent) SoftDelete(dbType DBType) SoftDelete {
+	return SoftDelete{Strategy: SoftDeleteVersionRow}
+}
+
+func TestSoftDeleteFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(d *Database) error
+		want string
+	}{
+		{
+			name: "timestamp",
+			run: func(d *Database) error {
+				var rows []sdTimestampRow
+				return d.Find(&rows).Error()
+			},
+			want: "SELECT * FROM `sd_rows` WHERE `sd_rows`.`deleted_at` IS NULL",
+		},
+		{
+			name: "flag",
+			run: func(d *Database) error {
+				var rows []sdFlagRow
+				return d.Find(&rows).Error()
+			},
+			want: "SELECT * FROM `sd_flags` WHERE `sd_flags`.`is_deleted` = ?",
+		},
+		{
+			name: "with deleted",
+			run: func(d *Database) error {
+				var rows []sdTimestampRow
+				return d.WithDeleted().Find(&rows).Error()
+			},
+			want: "SELECT * FROM `sd_rows`",
+		},
+		{
+			name: "only deleted",
+			run: func(d *Database) error {
+				var rows []sdTimestampRow
+				return d.OnlyDeleted().Find(&rows).Error()
+			},
+			want: "SELECT * FROM `sd_rows` WHERE `sd_rows`.`deleted_at` IS NOT NULL",
+		},
+		{
+			name: "delete",
+			run: func(d *Database) error {
+				return d.Delete(&sdFlagRow{ID: 1}).Error()
+			},
+			want: "UPDATE `sd_flags` SET `is_deleted`=? WHERE `id` = ?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, b := newFakeDB(t, Mysql)
+			if err := tt.run(NewDatabase(Mysql).Use(db)); err != nil {
+				t.Fatal(err)
+			}
+			stmts := b.statements()
+			if len(stmts) != 1 || stmts[0] != tt.want {
+				t.Errorf("statements %q, want %q", stmts, tt.want)
+			}
+		})
+	}
+}
+
+func TestSoftDeleteSaveDoesNotRestore(t *testing.T) {
+	db, b := newFakeDB(t, Mysql)
+	// 行已被软删除，更新不到任何行
+	b.exec = func(query string, args []driver.NamedValue) (int64, error) {
+		return 0, nil
+	}
+	row := &sdTimestampRow{ID: 1, Name: "restored"}
+	if err := NewDatabase(Mysql).Use(db).Save(row).Error(); err != nil {
+		t.Fatal(err)
+	}
+	stmts := b.statements()
+	if len(stmts) != 1 || !strings.Contains(stmts[0], "`sd_rows`.`deleted_at` IS NULL") {
+		t.Errorf("statements %q", stmts)
+	}
+	if b.count("INSERT") != 0 {
+		t.Errorf("save fell back to upsert: %q", stmts)
+	}
+}
+
+func TestVersionRowDeleteLoadsFullRow(t *testing.T) {
+	db, b := newFakeDB(t, Clickhouse)
+	b.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"id", "name", "is_deleted", "version"}, [][]driver.Value{{int64(1), "click", int64(0), int64(5)}}, nil
+	}
+	var inserted []driver.NamedValue
+	b.exec = func(query string, args []driver.NamedValue) (int64, error) {
+		inserted = args
+		return 1, nil
+	}
+	if err := NewDatabase(Clickhouse).Use(db).Delete(&sdEvent{ID: 1}).Error(); err != nil {
+		t.Fatal(err)
+	}
+	stmts := b.statements()
+	if len(stmts) != 2 {
+		t.Fatalf("statements %q", stmts)
+	}
+	if want := "SELECT * FROM `sd_events` FINAL WHERE `id` = ? AND `sd_events`.`is_deleted` = ?"; stmts[0] != want {
+		t.Errorf("load = %q, want %q", stmts[0], want)
+	}
+	if !strings.HasPrefix(stmts[1], "INSERT INTO `sd_events`") {
+		t.Errorf("insert = %q", stmts[1])
+	}
+	values := map[string]bool{}
+	for _, a := range inserted {
+		values[fmt.Sprint(a.Value)] = true
+	}
+	if !values["click"] || !values["1"] {
+		t.Errorf("inserted %v, want the loaded row with is_deleted = 1", inserted)
+	}
+}
+
+func TestVersionRowDeleteSkipsMissingRows(t *testing.T) {
+	db, b := newFakeDB(t, Clickhouse)
+	if err := NewDatabase(Clickhouse).Use(db).Delete(&sdEvent{ID: 1}).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if b.count("INSERT") != 0 {
+		t.Errorf("statements %q", b.statements())
+	}
+}
@@ -0,0 +1,111 @@
+package dac
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// SubQuery 基于 ConditionBuilder 的子查询，可作为 Condition.Value 使用
+type SubQuery struct {
+	table   string
+	fields  []string
+	builder *ConditionBuilder
+}
+
+// NewSubQuery 创建子查询，未传入字段时查询 *
+func NewSubQuery(table string, fields ...string) *SubQuery {
+	return &SubQuery{table: table, fields: fields}
+}
+
+// Where 设置子查询的条件
+func (sq *SubQuery) Where(builder *ConditionBuilder) *SubQuery {
+	sq.builder = builder
+	return sq
+}
+
+// Build 生成子查询语句
+func (sq *SubQuery) Build(dbType DBType) (string, []interface{}, error) {
+	fields := "*"
+	if len(sq.fields) > 0 {
+		fields = strings.Join(sq.fields, ",")
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", fields, sq.table)
+	if sq.builder == nil {
+		return query, nil, nil
+	}
+	where, args := sq.builder.Build(dbType)
+	if err := sq.builder.Error(); err != nil {
+		return "", nil, err
+	}
+	if where != "" {
+		query += " WHERE " + where
+	}
+	return query, args, nil
+}
+
+// isSubQuery 判断值是否为子查询
+func isSubQuery(value interface{}) bool {
+	switch value.(type) {
+	case *Database, *SubQuery:
+		return true
+	}
+	return false
+}
+
+// ColumnRef 列引用，作为 Condition.Value 时渲染为带引号的列名而不是绑定参数，用于列与列的比较
+type ColumnRef struct {
+	Table string // 表名或别名，可以为空
+	Name  string
+}
+
+// Column 创建列引用，name 可以是 column 或 alias.column
+func Column(name string) ColumnRef {
+	if i := strings.LastIndex(name, "."); i != -1 {
+		return ColumnRef{Table: name[:i], Name: name[i+1:]}
+	}
+	return ColumnRef{Name: name}
+}
+
+// ColumnOf 创建指定表的列引用，默认使用 TableAlias，与 TableWithAlias 的别名规则一致
+func ColumnOf(ti TableInfo, name string, alias ...string) ColumnRef {
+	table := ti.TableAlias()
+	if len(alias) > 0 && alias[0] != "" {
+		table = alias[0]
+	}
+	if table == "" {
+		table = ti.TableName()
+	}
+	return ColumnRef{Table: table, Name: name}
+}
+
+func (c ColumnRef) quoted(dbType DBType) string {
+	if c.Table == "" {
+		return quoteIdentifier(dbType, c.Name)
+	}
+	return quoteIdentifier(dbType, c.Table) + "." + quoteIdentifier(dbType, c.Name)
+}
+
+// parseValue 解析条件的值，子查询转换为 gorm 可以内联渲染的表达式，参数会合并到外层查询
+func parseValue(value interface{}, dbType DBType) (interface{}, error) {
+	switch v := value.(type) {
+	case *Database:
+		if v.err != nil {
+			return nil, v.err
+		}
+		if err := v.prepare(opQuery, nil); err != nil {
+			return nil, err
+		}
+		return v.DB(), nil
+	case *SubQuery:
+		query, args, err := v.Build(dbType)
+		if err != nil {
+			return nil, err
+		}
+		return clause.Expr{SQL: query, Vars: args}, nil
+	case ColumnRef:
+		return clause.Expr{SQL: v.quoted(dbType)}, nil
+	}
+	return value, nil
+}
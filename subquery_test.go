@@ -0,0 +1,84 @@
+package dac
+
+import (
+	"testing"
+
+	"gorm.io/gorm/clause"
+)
+
+type columnUser struct{}
+
+func (columnUser) TableName() string  { return "users" }
+func (columnUser) TableAlias() string { return "u" }
+
+type columnOrder struct{}
+
+func (columnOrder) TableName() string  { return "orders" }
+func (columnOrder) TableAlias() string { return "" }
+
+func TestColumnRef(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  ColumnRef
+		want ColumnRef
+	}{
+		{"column", Column("id"), ColumnRef{Name: "id"}},
+		{"qualified", Column("u.id"), ColumnRef{Table: "u", Name: "id"}},
+		{"schema qualified", Column("public.users.id"), ColumnRef{Table: "public.users", Name: "id"}},
+		{"table alias", ColumnOf(columnUser{}, "id"), ColumnRef{Table: "u", Name: "id"}},
+		{"explicit alias", ColumnOf(columnUser{}, "id", "x"), ColumnRef{Table: "x", Name: "id"}},
+		{"table name", ColumnOf(columnOrder{}, "id"), ColumnRef{Table: "orders", Name: "id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.ref != tt.want {
+				t.Errorf("got %+v, want %+v", tt.ref, tt.want)
+			}
+		})
+	}
+}
+
+func TestColumnRefCondition(t *testing.T) {
+	tests := []struct {
+		dbType DBType
+		want   string
+	}{
+		{Mysql, "SELECT * FROM `users` WHERE updated_at > `u`.`created_at` AND total <= `orders`.`limit`"},
+		{Postgres, `SELECT * FROM "users" WHERE updated_at > "u"."created_at" AND total <= "orders"."limit"`},
+		{Clickhouse, "SELECT * FROM `users` WHERE updated_at > `u`.`created_at` AND total <= `orders`.`limit`"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.dbType), func(t *testing.T) {
+			builder := NewConditionBuilder().
+				AppendCondition("updated_at", GreaterThan, Column("u.created_at")).
+				AppendCondition("total", LessThanOrEqual, ColumnOf(columnOrder{}, "limit"))
+			query, args, err := conditionSQL(t, tt.dbType, builder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != tt.want {
+				t.Errorf("query = %q, want %q", query, tt.want)
+			}
+			if len(args) != 0 {
+				t.Errorf("args %v, columns must not be bound", args)
+			}
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	v, err := parseValue(Column("o.id"), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr, ok := v.(clause.Expr); !ok || expr.SQL != `"o"."id"` || len(expr.Vars) != 0 {
+		t.Errorf("parseValue(Column) = %#v", v)
+	}
+	if v, err := parseValue(7, Mysql); err != nil || v != 7 {
+		t.Errorf("parseValue(7) = %v, %v", v, err)
+	}
+	sq := NewSubQuery("orders").Where(NewConditionBuilder().AppendCondition("id", Between, []int{1}))
+	if _, err := parseValue(sq, Mysql); err == nil {
+		t.Error("parseValue(SubQuery) did not return the builder error")
+	}
+}
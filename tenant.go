@@ -0,0 +1,198 @@
+package dac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// DefaultTenantColumn 模型未实现 TenantTableInfo 时，包含该字段的模型按租户隔离
+const DefaultTenantColumn = "tenant_id"
+
+var (
+	// ErrTenantRequired 开启 TenantRequired 后，按租户隔离的表在没有租户时执行语句
+	ErrTenantRequired = errors.New("tenant is required")
+
+	// TenantRequired 为 true 时，按租户隔离的表必须设置租户或使用 CrossTenant，否则返回 ErrTenantRequired
+	TenantRequired = false
+
+	// CrossTenantHook 跨租户执行语句时调用，用于审计，为 nil 时通过 gorm 的日志输出警告
+	CrossTenantHook func(ctx context.Context, event CrossTenantEvent)
+)
+
+// TenantTableInfo 表的租户字段，TenantColumn 返回空字符串时该表不按租户隔离
+type TenantTableInfo interface {
+	TableInfo
+	TenantColumn() string
+}
+
+// CrossTenantEvent 跨租户执行的语句
+type CrossTenantEvent struct {
+	Table     string
+	Operation string
+	Reason    string
+}
+
+var tenantTables sync.Map
+
+// RegisterTenantTable 设置表的租户字段，用于没有模型、只通过 Table 指定表名的语句，column 为空时取消注册
+// Table("orders AS o") 按表名 orders 查找，条件使用别名 o
+func RegisterTenantTable(table, column string) {
+	if column == "" {
+		tenantTables.Delete(table)
+		return
+	}
+	tenantTables.Store(table, column)
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant 在 context 中设置租户，通过 WithContext 传给 Database
+func ContextWithTenant(ctx context.Context, tenant interface{}) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext 获取 context 中的租户
+func TenantFromContext(ctx context.Context) (interface{}, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	tenant := ctx.Value(tenantKey{})
+	return tenant, tenant != nil
+}
+
+// WithContext 设置 context，context 中的租户、操作人等会在执行时生效
+func (d *Database) WithContext(ctx context.Context) *Database {
+	tx := d.getInstance()
+	return tx.useSourceDB(tx.db.WithContext(ctx))
+}
+
+// WithTenant 设置租户，之后的查询、更新、删除自动添加租户条件，创建时自动填充租户字段
+// 优先于 context 中的租户
+// 注意：租户条件只作用于语句的主表，Raw、Exec 和 Joins 连接的表不会添加，需要自行过滤
+func (d *Database) WithTenant(tenant interface{}) *Database {
+	tx := d.getInstance()
+	tx.tenant = tenant
+	return tx
+}
+
+// CrossTenant 不添加租户条件，用于管理后台等跨租户的操作，reason 会传给 CrossTenantHook 用于审计
+func (d *Database) CrossTenant(reason string) *Database {
+	tx := d.getInstance()
+	if reason == "" {
+		tx.err = errors.New("cross tenant requires a reason")
+		return tx
+	}
+	tx.crossTenant = reason
+	return tx
+}
+
+// currentTenant 获取当前租户，WithTenant 优先于 context
+func (d *Database) currentTenant() (interface{}, bool) {
+	if d.tenant != nil {
+		return d.tenant, true
+	}
+	return TenantFromContext(d.db.Statement.Context)
+}
+
+// tenantColumn 获取表的租户字段，表不按租户隔离时返回空字符串，table 为实际的表名而不是别名
+func tenantColumn(s *schema.Schema, table string) string {
+	if s != nil {
+		if ti, ok := reflect.New(s.ModelType).Interface().(TenantTableInfo); ok {
+			return ti.TenantColumn()
+		}
+	}
+	if column, ok := tenantTables.Load(table); ok {
+		return column.(string)
+	}
+	if s != nil && s.LookUpField(DefaultTenantColumn) != nil {
+		return DefaultTenantColumn
+	}
+	return ""
+}
+
+// applyTenant 按语句类型添加租户条件或填充租户字段
+func (d *Database) applyTenant(op operation, value interface{}) error {
+	if d.tenantApplied || d.derived {
+		return nil
+	}
+	s, table := d.target(value)
+	name := d.tableTag(value)
+	column := tenantColumn(s, name)
+	if column == "" {
+		return nil
+	}
+	if d.crossTenant != "" {
+		d.tenantApplied = true
+		event := CrossTenantEvent{Table: name, Operation: op.String(), Reason: d.crossTenant}
+		if CrossTenantHook != nil {
+			CrossTenantHook(d.db.Statement.Context, event)
+		} else {
+			d.db.Logger.Warn(d.db.Statement.Context, "cross tenant %s on %s: %s", event.Operation, event.Table, event.Reason)
+		}
+		return nil
+	}
+	tenant, ok := d.currentTenant()
+	if !ok {
+		if TenantRequired {
+			return fmt.Errorf("%w: %s on %s", ErrTenantRequired, op, table)
+		}
+		return nil
+	}
+	switch op {
+	case opCreate:
+		if err := setTenantValue(d, s, column, value, tenant); err != nil {
+			return err
+		}
+		return nil
+	case opUpdate:
+		if err := setTenantValue(d, s, column, value, tenant); err != nil {
+			return err
+		}
+	}
+	d.tenantApplied = true
+	d.db = d.db.Where(fmt.Sprintf("%s = ?", quoteIdentifier(d.DBType, table+"."+column)), tenant)
+	return nil
+}
+
+// setTenantValue 将租户写入创建或更新的值，支持结构体、结构体切片和 map
+// 更新时 value 为 nil 或单列的值，不做处理
+func setTenantValue(d *Database, s *schema.Schema, column string, value, tenant interface{}) error {
+	if value == nil {
+		return nil
+	}
+	switch m := value.(type) {
+	case map[string]interface{}:
+		m[column] = tenant
+		return nil
+	case []map[string]interface{}:
+		for _, row := range m {
+			row[column] = tenant
+		}
+		return nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	if rv.Kind() != reflect.Struct && rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	if s == nil || s.LookUpField(column) == nil {
+		return fmt.Errorf("model %T has no tenant column %s", value, column)
+	}
+	field := s.LookUpField(column)
+	ctx := d.db.Statement.Context
+	switch rv.Kind() {
+	case reflect.Struct:
+		return field.Set(ctx, rv, tenant)
+	default:
+		for i := 0; i < rv.Len(); i++ {
+			if err := field.Set(ctx, reflect.Indirect(rv.Index(i)), tenant); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type tenantOrder struct {
+	ID       uint
+	TenantID int64
+	Name     string
+}
+
+func TestTenantScoping(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(d *Database) error
+		want string
+	}{
+		{
+			name: "find",
+			run: func(d *Database) error {
+				var rows []tenantOrder
+				return d.Find(&rows).Error()
+			},
+			want: "SELECT * FROM `tenant_orders` WHERE `tenant_orders`.`tenant_id` = ?",
+		},
+		{
+			name: "update",
+			run: func(d *Database) error {
+				return d.Model(&tenantOrder{ID: 1}).Update("name", "x").Error()
+			},
+			want: "UPDATE `tenant_orders` SET `name`=? WHERE `tenant_orders`.`tenant_id` = ? AND `id` = ?",
+		},
+		{
+			name: "delete",
+			run: func(d *Database) error {
+				return d.Delete(&tenantOrder{ID: 1}).Error()
+			},
+			want: "DELETE FROM `tenant_orders` WHERE `tenant_orders`.`tenant_id` = ? AND `tenant_orders`.`id` = ?",
+		},
+		{
+			name: "aliased table",
+			run: func(d *Database) error {
+				var rows []map[string]interface{}
+				return d.Table("orders AS o").Find(&rows).Error()
+			},
+			want: "SELECT * FROM orders AS o WHERE `o`.`tenant_id` = ?",
+		},
+	}
+	RegisterTenantTable("orders", "tenant_id")
+	defer RegisterTenantTable("orders", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, b := newFakeDB(t, Mysql)
+			var args []driver.NamedValue
+			b.exec = func(query string, a []driver.NamedValue) (int64, error) {
+				args = a
+				return 1, nil
+			}
+			b.query = func(query string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+				args = a
+				return nil, nil, nil
+			}
+			if err := tt.run(NewDatabase(Mysql).Use(db).WithTenant(int64(7))); err != nil {
+				t.Fatal(err)
+			}
+			stmts := b.statements()
+			if len(stmts) != 1 || stmts[0] != tt.want {
+				t.Errorf("statements %q, want %q", stmts, tt.want)
+			}
+			found := false
+			for _, a := range args {
+				found = found || a.Value == int64(7)
+			}
+			if !found {
+				t.Errorf("args %v do not contain the tenant", args)
+			}
+		})
+	}
+}
+
+func TestTenantSaveDoesNotUpsert(t *testing.T) {
+	db, b := newFakeDB(t, Mysql)
+	// 行属于其他租户，更新不到任何行
+	b.exec = func(query string, args []driver.NamedValue) (int64, error) {
+		return 0, nil
+	}
+	row := &tenantOrder{ID: 1, TenantID: 8, Name: "overwrite"}
+	d := NewDatabase(Mysql).Use(db).WithTenant(int64(7)).Save(row)
+	if err := d.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.DB().RowsAffected; n != 0 {
+		t.Errorf("rows affected = %d, want 0", n)
+	}
+	for _, s := range b.statements() {
+		if !strings.HasPrefix(s, "UPDATE ") || !strings.Contains(s, "`tenant_orders`.`tenant_id` = ?") {
+			t.Errorf("unexpected statement %q", s)
+		}
+	}
+	if b.count("INSERT") != 0 || b.count("ON DUPLICATE") != 0 {
+		t.Errorf("save fell back to upsert: %q", b.statements())
+	}
+}
+
+func TestTenantRequired(t *testing.T) {
+	TenantRequired = true
+	defer func() { TenantRequired = false }()
+	db, b := newFakeDB(t, Mysql)
+	var rows []tenantOrder
+	err := NewDatabase(Mysql).Use(db).Find(&rows).Error()
+	if !errors.Is(err, ErrTenantRequired) {
+		t.Errorf("err = %v, want ErrTenantRequired", err)
+	}
+	if len(b.statements()) != 0 {
+		t.Errorf("statements %q", b.statements())
+	}
+}
@@ -0,0 +1,137 @@
+package dac
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Union 将多个查询以 UNION 合并（去重），结果包装为子查询，之后的 Order、Limit 作用于整个结果集
+// 各分支必须通过 Select 或 SelectExpr 指定字段，列数必须一致，可识别的列名或别名也必须一致
+func (d *Database) Union(others ...*Database) *Database {
+	return d.union(false, others)
+}
+
+// UnionAll 将多个查询以 UNION ALL 合并（不去重）
+func (d *Database) UnionAll(others ...*Database) *Database {
+	return d.union(true, others)
+}
+
+func (d *Database) union(all bool, others []*Database) *Database {
+	tx := d.getInstance()
+	result := tx.clone(tx.db.Session(&gorm.Session{NewDB: true}))
+	result.derived = true
+	branches := append([]*Database{tx}, others...)
+	columns, err := checkUnionColumns(branches)
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	// ClickHouse 中不带修饰的 UNION 依赖 union_default_mode 设置，需要显式写 DISTINCT
+	op := " UNION "
+	switch {
+	case all:
+		op = " UNION ALL "
+	case tx.DBType == Clickhouse:
+		op = " UNION DISTINCT "
+	}
+	parts := make([]string, len(branches))
+	vars := make([]interface{}, len(branches))
+	for i, b := range branches {
+		parts[i] = "?"
+		vars[i] = b.DB()
+	}
+	result.selectColumns = columns
+	result.unionBody = &clause.Expr{SQL: strings.Join(parts, op), Vars: vars}
+	wrapped := "(" + strings.Join(parts, ")"+op+"(") + ")"
+	return result.useSourceDB(result.db.Table(fmt.Sprintf("(%s) AS %s", wrapped, quoteIdentifier(tx.DBType, "dac_union")), vars...))
+}
+
+// checkUnionColumns 检查各分支的列数和列名，返回第一个分支的输出列名
+func checkUnionColumns(branches []*Database) ([]string, error) {
+	var first []string
+	for i, b := range branches {
+		if b.err != nil {
+			return nil, b.err
+		}
+		if err := b.prepare(opQuery, nil); err != nil {
+			return nil, err
+		}
+		if b.DBType != branches[0].DBType {
+			return nil, fmt.Errorf("union branch %d is %s, expected %s", i+1, b.DBType, branches[0].DBType)
+		}
+		if len(b.selectColumns) == 0 {
+			return nil, fmt.Errorf("union branch %d must specify columns with Select", i+1)
+		}
+		names := make([]string, len(b.selectColumns))
+		for j, column := range b.selectColumns {
+			names[j] = columnOutputName(column)
+		}
+		if i == 0 {
+			first = names
+			continue
+		}
+		if len(names) != len(first) {
+			return nil, fmt.Errorf("union branch %d selects %d columns, expected %d", i+1, len(names), len(first))
+		}
+		for j := range names {
+			if names[j] != "" && first[j] != "" && !strings.EqualFold(names[j], first[j]) {
+				return nil, fmt.Errorf("union branch %d column %d is %s, expected %s", i+1, j+1, names[j], first[j])
+			}
+		}
+	}
+	return first, nil
+}
+
+var (
+	aliasSuffixRe = regexp.MustCompile("(?i)\\s+as\\s+([\\w\"`]+)\\s*$")
+	plainColumnRe = regexp.MustCompile("^[\\w.\"`]+$")
+)
+
+// columnOutputName 获取字段的输出列名，无法识别的表达式返回空字符串
+func columnOutputName(field string) string {
+	field = strings.TrimSpace(field)
+	if match := aliasSuffixRe.FindStringSubmatch(field); match != nil {
+		return strings.Trim(match[1], "\"`")
+	}
+	if plainColumnRe.MatchString(field) {
+		parts := strings.Split(field, ".")
+		return strings.Trim(parts[len(parts)-1], "\"`")
+	}
+	return ""
+}
+
+// splitTopLevel 按逗号拆分字段列表，忽略括号和引号中的逗号
+func splitTopLevel(s string) []string {
+	var (
+		parts []string
+		depth int
+		quote rune
+		start int
+	)
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
@@ -0,0 +1,88 @@
+package dac
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		name   string
+		dbType DBType
+		all    bool
+		want   string
+	}{
+		{"mysql union", Mysql, false, "SELECT * FROM ((SELECT id,name FROM `users` WHERE age > ?) UNION (SELECT id,name FROM `admins` WHERE age > ?)) AS `dac_union` ORDER BY id"},
+		{"mysql union all", Mysql, true, "SELECT * FROM ((SELECT id,name FROM `users` WHERE age > ?) UNION ALL (SELECT id,name FROM `admins` WHERE age > ?)) AS `dac_union` ORDER BY id"},
+		{"postgres union", Postgres, false, `SELECT * FROM ((SELECT id,name FROM "users" WHERE age > $1) UNION (SELECT id,name FROM "admins" WHERE age > $2)) AS "dac_union" ORDER BY id`},
+		{"clickhouse union", Clickhouse, false, "SELECT * FROM ((SELECT id,name FROM `users` WHERE age > ?) UNION DISTINCT (SELECT id,name FROM `admins` WHERE age > ?)) AS `dac_union` ORDER BY id"},
+		{"clickhouse union all", Clickhouse, true, "SELECT * FROM ((SELECT id,name FROM `users` WHERE age > ?) UNION ALL (SELECT id,name FROM `admins` WHERE age > ?)) AS `dac_union` ORDER BY id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, b := newFakeDB(t, tt.dbType)
+			var args []driver.NamedValue
+			b.query = func(query string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+				args = a
+				return nil, nil, nil
+			}
+			users := NewDatabase(tt.dbType).Use(db).Table("users").Select("id", "name").Query("age > ?", 18)
+			admins := NewDatabase(tt.dbType).Use(db).Table("admins").Select("id", "name").Query("age > ?", 30)
+			union := users.Union(admins)
+			if tt.all {
+				union = users.UnionAll(admins)
+			}
+			var out []map[string]interface{}
+			if err := union.Order("id").Find(&out).Error(); err != nil {
+				t.Fatal(err)
+			}
+			if stmts := b.statements(); len(stmts) != 1 || stmts[0] != tt.want {
+				t.Errorf("statements %q, want %q", stmts, tt.want)
+			}
+			if len(args) != 2 || args[0].Value != int64(18) || args[1].Value != int64(30) {
+				t.Errorf("args %v, want [18 30]", args)
+			}
+		})
+	}
+}
+
+func TestUnionColumnsMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []string
+		want   string
+	}{
+		{"column count", []string{"id"}, "selects 1 columns, expected 2"},
+		{"column name", []string{"id", "email"}, "column 2 is email, expected name"},
+		{"no select", nil, "must specify columns with Select"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, b := newFakeDB(t, Mysql)
+			users := NewDatabase(Mysql).Use(db).Table("users").Select("id", "name")
+			admins := NewDatabase(Mysql).Use(db).Table("admins")
+			if tt.fields != nil {
+				admins = admins.Select(tt.fields...)
+			}
+			var out []map[string]interface{}
+			err := users.Union(admins).Find(&out).Error()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+			if b.count("UNION") != 0 {
+				t.Errorf("statements %q, want no union query", b.statements())
+			}
+		})
+	}
+}
+
+func TestUnionAliasedColumns(t *testing.T) {
+	db, _ := newFakeDB(t, Mysql)
+	users := NewDatabase(Mysql).Use(db).Table("users").Select("id", "name AS label")
+	admins := NewDatabase(Mysql).Use(db).Table("admins").Select("admins.id", "concat(first, last) AS label")
+	var out []map[string]interface{}
+	if err := users.Union(admins).Find(&out).Error(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,106 @@
+package dac
+
+import (
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultBatchSize 默认的批量写入条数
+const DefaultBatchSize = 1000
+
+// maxBindParams 单条语句允许的最大绑定参数个数，0 表示不限制
+func maxBindParams(dbType DBType) int {
+	switch dbType {
+	case Mysql, Postgres:
+		return 65535
+	default:
+		return 0
+	}
+}
+
+// BatchSize 设置批量写入的条数，实际条数还会受数据库绑定参数上限的限制
+func (d *Database) BatchSize(size int) *Database {
+	tx := d.getInstance()
+	tx.batchSize = size
+	return tx
+}
+
+// insertBatchSize 计算批量写入的条数，保证每批的参数个数不超过数据库上限
+func (d *Database) insertBatchSize(rows interface{}) int {
+	size := d.batchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	limit := maxBindParams(d.DBType)
+	if limit == 0 {
+		return size
+	}
+	columns := 0
+	value := reflect.Indirect(reflect.ValueOf(rows))
+	if value.Kind() == reflect.Slice && value.Len() > 0 {
+		if m, ok := reflect.Indirect(value.Index(0)).Interface().(map[string]interface{}); ok {
+			columns = len(m)
+		}
+	}
+	if columns == 0 {
+		stmt := &gorm.Statement{DB: d.db}
+		if err := stmt.Parse(rows); err == nil {
+			columns = len(stmt.Schema.DBNames)
+		}
+	}
+	if columns > 0 && size*columns > limit {
+		size = limit / columns
+	}
+	return size
+}
+
+// Upsert 批量插入，与 conflictColumns 冲突时更新 updateColumns，updateColumns 为空时忽略冲突的行
+// MySQL、PostgreSQL 使用 clause.OnConflict，由方言渲染为 ON DUPLICATE KEY UPDATE 或 ON CONFLICT (...) DO UPDATE，
+// MySQL 的冲突由表上的主键或唯一索引决定
+// 注意：ClickHouse 没有冲突处理，这里只做普通插入，依赖 ReplacingMergeTree 在合并时按排序键去重，
+// 合并完成前查询会读到重复的行，需要使用 FINAL 或 argMax 读取最新版本
+func (d *Database) Upsert(rows interface{}, conflictColumns []string, updateColumns []string) *Database {
+	tx := d.getInstance()
+	defer tx.observe("upsert", rows)()
+	if tx.prepare(opCreate, rows) != nil {
+		return tx
+	}
+	db := tx.db
+	switch tx.DBType {
+	case Mysql, Postgres:
+		if len(conflictColumns) == 0 {
+			tx.err = fmt.Errorf("upsert on %s requires conflict columns", tx.DBType)
+			return tx
+		}
+		onConflict := clause.OnConflict{DoNothing: len(updateColumns) == 0}
+		for _, column := range conflictColumns {
+			onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+		}
+		if len(updateColumns) > 0 {
+			onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+		}
+		db = db.Clauses(onConflict)
+	case Clickhouse:
+	default:
+		tx.err = fmt.Errorf("upsert is not supported on %s", tx.DBType)
+		return tx
+	}
+	tx.useSourceDB(db.CreateInBatches(rows, tx.insertBatchSize(rows)))
+	tx.invalidate(rows)
+	return tx
+}
+
+// BatchCreate 批量插入，每批条数由 BatchSize 和数据库绑定参数上限决定
+func (d *Database) BatchCreate(rows interface{}) *Database {
+	tx := d.getInstance()
+	defer tx.observe("batch_create", rows)()
+	if tx.prepare(opCreate, rows) != nil {
+		return tx
+	}
+	tx.useSourceDB(tx.db.CreateInBatches(rows, tx.insertBatchSize(rows)))
+	tx.invalidate(rows)
+	return tx
+}
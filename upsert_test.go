@@ -0,0 +1,47 @@
+package dac
+
+import (
+	"strings"
+	"testing"
+)
+
+type upsertRow struct {
+	ID    uint `gorm:"primaryKey"`
+	Email string
+	Name  string
+}
+
+func TestUpsertPostgres(t *testing.T) {
+	tests := []struct {
+		name   string
+		update []string
+		want   string
+	}{
+		{"update", []string{"name"}, `ON CONFLICT ("email") DO UPDATE SET "name"="excluded"."name"`},
+		{"ignore", nil, `ON CONFLICT ("email") DO NOTHING`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, b := newFakeDB(t, Postgres)
+			rows := []upsertRow{{ID: 1, Email: "a@x.com", Name: "a"}}
+			if err := NewDatabase(Postgres).Use(db).Upsert(&rows, []string{"email"}, tt.update).Error(); err != nil {
+				t.Fatal(err)
+			}
+			if b.count(tt.want) != 1 {
+				t.Errorf("statements %q, want %q", b.statements(), tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsertRequiresConflictColumns(t *testing.T) {
+	db, b := newFakeDB(t, Postgres)
+	rows := []upsertRow{{ID: 1}}
+	err := NewDatabase(Postgres).Use(db).Upsert(&rows, nil, []string{"name"}).Error()
+	if err == nil || !strings.Contains(err.Error(), "conflict columns") {
+		t.Errorf("err = %v", err)
+	}
+	if len(b.statements()) != 0 {
+		t.Errorf("statements %q", b.statements())
+	}
+}
@@ -1,8 +1,11 @@
 package dac
 
 import (
+	"database/sql/driver"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func convertToSQLFormat(input interface{}) string {
@@ -32,6 +35,100 @@ func wrapIdentifier(identifier string) string {
 		return identifier // 默认返回原始标识符
 	}
 }
+
+// quoteIdentifier 按数据库类型为标识符加引号，支持 table.column 形式
+func quoteIdentifier(dbType DBType, identifier string) string {
+	parts := strings.Split(identifier, ".")
+	for i, part := range parts {
+		if part == "*" {
+			continue
+		}
+		switch dbType {
+		case Postgres:
+			parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+		default:
+			parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// inlineArgs 将 SQL 中的 ? 依次替换为参数的字面量，引号内的 ? 不做替换
+func inlineArgs(dbType DBType, sql string, args []interface{}) (string, error) {
+	if len(args) == 0 {
+		return sql, nil
+	}
+	var (
+		b     strings.Builder
+		quote rune
+		idx   int
+	)
+	for _, r := range sql {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			if idx >= len(args) {
+				return "", fmt.Errorf("not enough args for %q", sql)
+			}
+			literal, err := sqlLiteral(dbType, args[idx])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(literal)
+			idx++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if idx != len(args) {
+		return "", fmt.Errorf("too many args for %q", sql)
+	}
+	return b.String(), nil
+}
+
+// sqlLiteral 将参数转换为对应数据库的字面量
+func sqlLiteral(dbType DBType, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case string:
+		return quoteString(dbType, v), nil
+	case time.Time:
+		return quoteString(dbType, v.Format("2006-01-02 15:04:05.999999")), nil
+	case driver.Valuer:
+		dv, err := v.Value()
+		if err != nil {
+			return "", err
+		}
+		return sqlLiteral(dbType, dv)
+	}
+	return "", fmt.Errorf("unsupported literal type %T", value)
+}
+
+// quoteString 字符串字面量，MySQL 和 ClickHouse 中反斜杠是转义字符，PostgreSQL 中不是
+func quoteString(dbType DBType, s string) string {
+	if dbType != Postgres {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
 func checkFirstLast(s, substr string) bool {
 	if len(s) < len(substr) {
 		return false